@@ -0,0 +1,140 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+)
+
+const (
+	maxPlacementBatch = 1000
+	// maxPlacementBodySize fits a batch of the longest object IDs with room for formatting.
+	maxPlacementBodySize = 64 << 10
+	// placementWorkers bounds the objects whose existence is checked at once by a batch lookup.
+	placementWorkers = 16
+)
+
+type placement struct {
+	ID          string `json:"id"`
+	RingVersion uint64 `json:"ringVersion"`
+	// Partition is only reported by rings placing objects by partitions.
+	Partition *int               `json:"partition,omitempty"`
+	Storages  []storagePlacement `json:"storages"`
+}
+
+type storagePlacement struct {
+	ID      string `json:"id"`
+	Primary bool   `json:"primary"`
+	Exists  bool   `json:"exists"`
+	// Error is set when existence couldn't be checked.
+	Error string `json:"error,omitempty"`
+}
+
+type batchPlacementRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type batchPlacementResponse struct {
+	Placements []placement `json:"placements"`
+	// PrimaryCounts counts the looked up objects each storage is the primary owner of.
+	PrimaryCounts map[string]int `json:"primaryCounts"`
+}
+
+func getPlacement(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ring, replicas := objectDistributor.Ring()
+		httputil.WriteJSON(w, http.StatusOK, locate(r.Context(), objectDistributor, ring, replicas, mux.Vars(r)["id"]))
+	}
+}
+
+func batchPlacement(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var request batchPlacementRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPlacementBodySize)).Decode(&request); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(request.IDs) == 0 || len(request.IDs) > maxPlacementBatch {
+			http.Error(w, "ids has to contain between 1 and 1000 object IDs", http.StatusBadRequest)
+			return
+		}
+		for _, objectID := range request.IDs {
+			if !core.ValidObjectID(objectID) {
+				http.Error(w, "invalid object ID '"+objectID+"'", http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Every object is located on the same ring, so placements are comparable.
+		ring, replicas := objectDistributor.Ring()
+		response := batchPlacementResponse{
+			Placements:    make([]placement, len(request.IDs)),
+			PrimaryCounts: make(map[string]int),
+		}
+
+		ids := make(chan int)
+		var wg sync.WaitGroup
+		for i := 0; i < placementWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range ids {
+					response.Placements[i] = locate(r.Context(), objectDistributor, ring, replicas, request.IDs[i])
+				}
+			}()
+		}
+		for i := range request.IDs {
+			ids <- i
+		}
+		close(ids)
+		wg.Wait()
+
+		for _, p := range response.Placements {
+			if len(p.Storages) > 0 {
+				response.PrimaryCounts[p.Storages[0].ID]++
+			}
+		}
+		httputil.WriteJSON(w, http.StatusOK, response)
+	}
+}
+
+// locate returns the owners of the object on the ring and checks whether they hold it.
+func locate(ctx context.Context, objectDistributor *distributor.ObjectDistributor, ring distributor.Ring, replicas int, objectID string) placement {
+	p := placement{
+		ID:          objectID,
+		RingVersion: ring.Version(),
+		Storages:    make([]storagePlacement, 0, replicas),
+	}
+	if partitioned, ok := ring.(distributor.PartitionedRing); ok {
+		partition := partitioned.LocatePartition(objectID)
+		p.Partition = &partition
+	}
+
+	for i, storageID := range ring.LocateStorages(objectID, replicas) {
+		sp := storagePlacement{ID: storageID, Primary: i == 0}
+		storage, ok := objectDistributor.Storage(storageID)
+		if !ok {
+			// The storage left the ring after it was snapshotted.
+			sp.Error = "storage is no longer registered"
+			p.Storages = append(p.Storages, sp)
+			continue
+		}
+
+		switch _, err := storage.Stat(ctx, objectID); err {
+		case nil:
+			sp.Exists = true
+		case core.ErrNotFound:
+		// Ok
+		default:
+			sp.Error = err.Error()
+		}
+		p.Storages = append(p.Storages, sp)
+	}
+	return p
+}
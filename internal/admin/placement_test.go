@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacement(t *testing.T) {
+	objectDistributor := distributor.NewObjectDistributor(util.NewConsistentHashStorageSelector(), distributor.WithReplication(2, 2))
+	for _, storageID := range []string{"a", "b", "c"} {
+		objectDistributor.AddStorage(storageID, memory.NewObjectStorage(), 1)
+	}
+	_, err := objectDistributor.PutObject(context.Background(), "stored", strings.NewReader("blob"), 4, distributor.PutOptions{})
+	require.NoError(t, err)
+
+	router := Router(objectDistributor, func() []util.NodeStatus { return nil }, distributor.NewRebalancer(objectDistributor, 0).Progress, 1)
+
+	t.Run("object owners are returned with existence", func(t *testing.T) {
+		w := serve(router, "/admin/placement/stored")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var p placement
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		assert.Equal(t, "stored", p.ID)
+		assert.Equal(t, uint64(3), p.RingVersion)
+		require.NotNil(t, p.Partition)
+
+		owners, _ := objectDistributor.Ring()
+		require.Len(t, p.Storages, 2)
+		for i, storageID := range owners.LocateStorages("stored", 2) {
+			assert.Equal(t, storagePlacement{ID: storageID, Primary: i == 0, Exists: true}, p.Storages[i])
+		}
+	})
+
+	t.Run("when object isn't stored, owners are returned without it", func(t *testing.T) {
+		var p placement
+		require.NoError(t, json.Unmarshal(serve(router, "/admin/placement/missing").Body.Bytes(), &p))
+		require.Len(t, p.Storages, 2)
+		assert.False(t, p.Storages[0].Exists)
+		assert.False(t, p.Storages[1].Exists)
+	})
+
+	t.Run("batch lookup returns placements in request order with primary counts", func(t *testing.T) {
+		body, err := json.Marshal(batchPlacementRequest{IDs: []string{"stored", "missing", "other"}})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/placement", bytes.NewReader(body)))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response batchPlacementResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Placements, 3)
+		assert.Equal(t, "stored", response.Placements[0].ID)
+		assert.True(t, response.Placements[0].Storages[0].Exists)
+		assert.Equal(t, "other", response.Placements[2].ID)
+
+		total := 0
+		for _, count := range response.PrimaryCounts {
+			total += count
+		}
+		assert.Equal(t, 3, total)
+	})
+
+	t.Run("when batch contains invalid ID, should return bad request", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/placement", strings.NewReader(`{"ids":["not/valid"]}`)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("when body is too large, should return bad request", func(t *testing.T) {
+		body := `{"ids":["stored"],"padding":"` + strings.Repeat(" ", maxPlacementBodySize) + `"}`
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/placement", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,139 @@
+// Package admin serves the probes and status endpoints used to operate the gateway.
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+)
+
+// NodesFn returns the status of known storage nodes.
+type NodesFn func() []util.NodeStatus
+
+// ProgressFn returns the progress of the current or last rebalance.
+type ProgressFn func() distributor.RebalanceProgress
+
+type readiness struct {
+	Ready         bool `json:"ready"`
+	HealthyNodes  int  `json:"healthyNodes"`
+	MinReadyNodes int  `json:"minReadyNodes"`
+}
+
+type clusterStatus struct {
+	RingVersion uint64                        `json:"ringVersion"`
+	Replicas    int                           `json:"replicas"`
+	Partitions  int                           `json:"partitions"`
+	Storages    []storageStatus               `json:"storages"`
+	Rebalance   distributor.RebalanceProgress `json:"rebalance"`
+}
+
+type storageStatus struct {
+	ID        string    `json:"id"`
+	Address   string    `json:"address"`
+	Zone      string    `json:"zone,omitempty"`
+	Weight    float64   `json:"weight"`
+	Healthy   bool      `json:"healthy"`
+	LastCheck time.Time `json:"lastCheck"`
+	// Partitions are all partitions the storage keeps a replica of, the primary ones included.
+	Partitions        []int `json:"partitions"`
+	PrimaryPartitions []int `json:"primaryPartitions"`
+}
+
+// Router serves liveness and readiness probes, the cluster status and object placement lookups.
+// The gateway is ready once at least minReadyNodes storage nodes are healthy.
+func Router(objectDistributor *distributor.ObjectDistributor, nodesFn NodesFn, progressFn ProgressFn, minReadyNodes int) http.Handler {
+	r := mux.NewRouter()
+	r.HandleFunc("/healthz", healthz).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/readyz", readyz(nodesFn, minReadyNodes)).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/admin/cluster", cluster(objectDistributor, nodesFn, progressFn)).Methods(http.MethodGet)
+	r.HandleFunc("/admin/placement", batchPlacement(objectDistributor)).Methods(http.MethodPost)
+	r.HandleFunc("/admin/placement/{id:[a-zA-Z0-9]{1,32}}", getPlacement(objectDistributor)).Methods(http.MethodGet)
+	return r
+}
+
+// healthz only tells the process is serving requests, storage problems are left to readyz.
+func healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok\n"))
+}
+
+func readyz(nodesFn NodesFn, minReadyNodes int) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		healthy := 0
+		for _, status := range nodesFn() {
+			if status.Healthy {
+				healthy++
+			}
+		}
+
+		response := readiness{
+			Ready:         healthy >= minReadyNodes,
+			HealthyNodes:  healthy,
+			MinReadyNodes: minReadyNodes,
+		}
+		if !response.Ready {
+			httputil.WriteJSON(w, http.StatusServiceUnavailable, response)
+			return
+		}
+		httputil.WriteJSON(w, http.StatusOK, response)
+	}
+}
+
+func cluster(objectDistributor *distributor.ObjectDistributor, nodesFn NodesFn, progressFn ProgressFn) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ring, replicas := objectDistributor.Ring()
+		response := clusterStatus{
+			RingVersion: ring.Version(),
+			Replicas:    replicas,
+			Storages:    make([]storageStatus, 0),
+			Rebalance:   progressFn(),
+		}
+
+		owned, primary := make(map[string][]int), make(map[string][]int)
+		if partitioned, ok := ring.(distributor.PartitionedRing); ok {
+			response.Partitions = partitioned.PartitionCount()
+			owned, primary = partitionOwners(partitioned, replicas)
+		}
+
+		for _, status := range nodesFn() {
+			storage := storageStatus{
+				ID:                status.Node.ID,
+				Address:           status.Node.Address,
+				Zone:              status.Node.Zone,
+				Weight:            status.Node.Weight,
+				Healthy:           status.Healthy,
+				LastCheck:         status.LastCheck.UTC(),
+				Partitions:        owned[status.Node.ID],
+				PrimaryPartitions: primary[status.Node.ID],
+			}
+			if storage.Partitions == nil {
+				storage.Partitions = make([]int, 0)
+			}
+			if storage.PrimaryPartitions == nil {
+				storage.PrimaryPartitions = make([]int, 0)
+			}
+			response.Storages = append(response.Storages, storage)
+		}
+
+		httputil.WriteJSON(w, http.StatusOK, response)
+	}
+}
+
+// partitionOwners maps storage IDs to the partitions they keep a replica of and to the ones they are the primary owner of.
+func partitionOwners(ring distributor.PartitionedRing, replicas int) (map[string][]int, map[string][]int) {
+	owned, primary := make(map[string][]int), make(map[string][]int)
+	for partitionID := 0; partitionID < ring.PartitionCount(); partitionID++ {
+		for i, storageID := range ring.PartitionOwners(partitionID, replicas) {
+			owned[storageID] = append(owned[storageID], partitionID)
+			if i == 0 {
+				primary[storageID] = append(primary[storageID], partitionID)
+			}
+		}
+	}
+	return owned, primary
+}
@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(handler http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestRouter(t *testing.T) {
+	checkedAt := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+	nodes := []util.NodeStatus{
+		{Node: util.StorageNode{ID: "a", Address: "a:9000", Weight: 1}, Healthy: true, LastCheck: checkedAt},
+		{Node: util.StorageNode{ID: "b", Address: "b:9000", Weight: 1}, Healthy: false, LastCheck: checkedAt},
+	}
+	nodesFn := func() []util.NodeStatus { return nodes }
+	progressFn := func() distributor.RebalanceProgress { return distributor.RebalanceProgress{CopiedObjects: 3} }
+
+	objectDistributor := distributor.NewObjectDistributor(util.NewConsistentHashStorageSelector(), distributor.WithReplication(2, 1))
+	objectDistributor.AddStorage("a", memory.NewObjectStorage(), 1)
+
+	t.Run("liveness doesn't depend on storages", func(t *testing.T) {
+		router := Router(objectDistributor, func() []util.NodeStatus { return nil }, progressFn, 1)
+		assert.Equal(t, http.StatusOK, serve(router, "/healthz").Code)
+	})
+
+	t.Run("when enough nodes are healthy, gateway is ready", func(t *testing.T) {
+		w := serve(Router(objectDistributor, nodesFn, progressFn, 1), "/readyz")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"ready":true,"healthyNodes":1,"minReadyNodes":1}`, w.Body.String())
+	})
+
+	t.Run("when too few nodes are healthy, gateway isn't ready", func(t *testing.T) {
+		w := serve(Router(objectDistributor, nodesFn, progressFn, 2), "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.JSONEq(t, `{"ready":false,"healthyNodes":1,"minReadyNodes":2}`, w.Body.String())
+	})
+
+	t.Run("cluster status lists storages with owned partitions", func(t *testing.T) {
+		w := serve(Router(objectDistributor, nodesFn, progressFn, 1), "/admin/cluster")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var status clusterStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		assert.Equal(t, 2, status.Replicas)
+		assert.Equal(t, 7, status.Partitions)
+		assert.Equal(t, 3, status.Rebalance.CopiedObjects)
+		require.Len(t, status.Storages, 2)
+
+		// The only storage in the ring owns every partition, the unhealthy one was removed from it.
+		assert.Equal(t, "a", status.Storages[0].ID)
+		assert.True(t, status.Storages[0].Healthy)
+		assert.Equal(t, checkedAt, status.Storages[0].LastCheck)
+		assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6}, status.Storages[0].Partitions)
+		assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6}, status.Storages[0].PrimaryPartitions)
+		assert.Equal(t, "b", status.Storages[1].ID)
+		assert.False(t, status.Storages[1].Healthy)
+		assert.Empty(t, status.Storages[1].Partitions)
+	})
+}
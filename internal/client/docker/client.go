@@ -6,7 +6,7 @@ import (
 	"strings"
 
 	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
 	"github.com/spacelift-io/homework-object-storage/internal/util"
 )
@@ -21,19 +21,47 @@ func NewClient(cli *client.Client) *Client {
 	}
 }
 
-func (c *Client) SearchContainers(ctx context.Context, name string) ([]util.Container, error) {
-	dockerContainers, err := c.cli.ContainerList(ctx, types.ContainerListOptions{})
-	if err != nil {
-		return nil, err
+// ContainerFilter selects containers by a name substring and by labels, empty fields match every container.
+type ContainerFilter struct {
+	NameContains string
+	// Labels have to be present with the given value, an empty value only requires the label to be present.
+	Labels map[string]string
+}
+
+func (f ContainerFilter) matches(names []string, labels map[string]string) bool {
+	for key, value := range f.Labels {
+		actual, ok := labels[key]
+		if !ok || (value != "" && actual != value) {
+			return false
+		}
+	}
+
+	for _, n := range names {
+		if strings.Contains(n, f.NameContains) {
+			return true
+		}
 	}
+	return false
+}
 
-	namesContain := func(name string, names []string) bool {
-		for _, n := range names {
-			if strings.Contains(n, name) {
-				return true
-			}
+func (f ContainerFilter) listFilters() filters.Args {
+	args := filters.NewArgs()
+	for key, value := range f.Labels {
+		if value == "" {
+			args.Add("label", key)
+			continue
 		}
-		return false
+		args.Add("label", fmt.Sprintf("%s=%s", key, value))
+	}
+	return args
+}
+
+func (c *Client) SearchContainers(ctx context.Context, filter ContainerFilter) ([]util.Container, error) {
+	dockerContainers, err := c.cli.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filter.listFilters(),
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	containers := make([]util.Container, 0)
@@ -42,7 +70,7 @@ func (c *Client) SearchContainers(ctx context.Context, name string) ([]util.Cont
 			continue
 		}
 
-		if !namesContain(name, dc.Names) {
+		if !filter.matches(dc.Names, dc.Labels) {
 			continue
 		}
 
@@ -61,22 +89,28 @@ func (c *Client) getContainer(ctx context.Context, containerID string) (util.Con
 		return util.Container{}, err
 	}
 
-	var networkSettings *network.EndpointSettings
-	for _, networkSettings = range containerJson.NetworkSettings.Networks {
+	// Containers which aren't connected to any network yet have no IP.
+	ip := ""
+	for _, networkSettings := range containerJson.NetworkSettings.Networks {
+		if networkSettings != nil {
+			ip = networkSettings.IPAddress
+		}
 		break
 	}
 
 	return util.Container{
+		ID:          containerJson.ID,
 		Name:        containerJson.Name,
-		IP:          networkSettings.IPAddress,
+		IP:          ip,
 		Environment: parseEnvVars(containerJson.Config.Env),
+		Labels:      containerJson.Config.Labels,
 	}, nil
 }
 
 func parseEnvVars(strs []string) map[string]string {
 	kvs := make(map[string]string)
 	for _, kv := range strs {
-		parts := strings.Split(kv, "=")
+		parts := strings.SplitN(kv, "=", 2)
 		if len(parts) != 2 {
 			continue
 		}
@@ -3,6 +3,7 @@ package docker
 import (
 	"context"
 	"github.com/docker/docker/client"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/testcontainers/testcontainers-go"
@@ -30,7 +31,7 @@ func TestDockerClient(t *testing.T) {
 
 	dClient := NewClient(cli)
 
-	containers, err := dClient.SearchContainers(context.Background(), "nginx")
+	containers, err := dClient.SearchContainers(context.Background(), ContainerFilter{NameContains: "nginx"})
 	require.NoError(t, err)
 
 	for _, c := range containers {
@@ -77,3 +78,19 @@ func createGenericContainer(ctx context.Context, envVariables map[string]string)
 			container.Terminate(context.Background())
 		}, nil
 }
+
+func TestParseEnvVars(t *testing.T) {
+	env := parseEnvVars([]string{"MINIO_ROOT_USER=root", "MINIO_ROOT_PASSWORD=cGFzc3dvcmQ=", "EMPTY=", "INVALID"})
+	assert.Equal(t, map[string]string{
+		"MINIO_ROOT_USER":     "root",
+		"MINIO_ROOT_PASSWORD": "cGFzc3dvcmQ=",
+		"EMPTY":               "",
+	}, env)
+
+	t.Run("when password contains '=', credentials are resolved with the whole value", func(t *testing.T) {
+		resolver := util.NewEnvCredentialResolver(nil, util.DefaultCredentialEnvs...)
+		creds, err := resolver.Resolve(context.Background(), util.Container{Name: "/node", Environment: env}, util.StorageNode{})
+		require.NoError(t, err)
+		assert.Equal(t, util.Credentials{AccessKey: "root", SecretKey: "cGFzc3dvcmQ="}, creds)
+	})
+}
@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+)
+
+// WatchContainers streams lifecycle events of containers matching the filter which happened since the given
+// time, until ctx is done or the event stream breaks. Started containers are inspected before being reported,
+// those which can't be are skipped. The time of the last received event is returned, or since if there was
+// none, so the stream can be resubscribed without missing events.
+func (c *Client) WatchContainers(ctx context.Context, filter ContainerFilter, since time.Time, onEvent func(event util.ContainerEvent)) (time.Time, error) {
+	opts := types.EventsOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("type", string(events.ContainerEventType)),
+			filters.Arg("type", string(events.NetworkEventType)),
+			filters.Arg("event", "start"),
+			filters.Arg("event", "stop"),
+			filters.Arg("event", "die"),
+			filters.Arg("event", "connect"),
+		),
+	}
+	if !since.IsZero() {
+		// The bound is inclusive, so the last event seen is delivered again, which handlers tolerate.
+		opts.Since = fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond())
+	}
+	messages, errs := c.cli.Events(ctx, opts)
+
+	last := since
+	for {
+		select {
+		case err := <-errs:
+			return last, err
+		case msg := <-messages:
+			last = time.Unix(0, msg.TimeNano)
+			event, ok, err := c.toContainerEvent(ctx, filter, msg)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"container": msg.Actor.ID,
+					"action":    msg.Action,
+				}).WithError(err).Error("handling container event")
+				continue
+			}
+			if ok {
+				onEvent(event)
+			}
+		}
+	}
+}
+
+func (c *Client) toContainerEvent(ctx context.Context, filter ContainerFilter, msg events.Message) (util.ContainerEvent, bool, error) {
+	switch {
+	case msg.Type == events.ContainerEventType && (msg.Action == "stop" || msg.Action == "die"):
+		// Container events carry the container labels among their attributes.
+		if !filter.matches([]string{msg.Actor.Attributes["name"]}, msg.Actor.Attributes) {
+			return util.ContainerEvent{}, false, nil
+		}
+		return util.ContainerEvent{
+			Type:      util.ContainerStopped,
+			Container: util.Container{ID: msg.Actor.ID, Name: msg.Actor.Attributes["name"]},
+		}, true, nil
+	case msg.Type == events.ContainerEventType && msg.Action == "start":
+		return c.startedEvent(ctx, msg.Actor.ID, filter)
+	case msg.Type == events.NetworkEventType && msg.Action == "connect":
+		// Network events are about the network, the container is only referenced by its ID.
+		return c.startedEvent(ctx, msg.Actor.Attributes["container"], filter)
+	default:
+		return util.ContainerEvent{}, false, nil
+	}
+}
+
+func (c *Client) startedEvent(ctx context.Context, containerID string, filter ContainerFilter) (util.ContainerEvent, bool, error) {
+	container, err := c.getContainer(ctx, containerID)
+	if err != nil {
+		return util.ContainerEvent{}, false, err
+	}
+	if !filter.matches([]string{container.Name}, container.Labels) || container.IP == "" {
+		return util.ContainerEvent{}, false, nil
+	}
+
+	return util.ContainerEvent{
+		Type:      util.ContainerStarted,
+		Container: container,
+	}, true, nil
+}
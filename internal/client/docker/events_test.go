@@ -0,0 +1,55 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerClientWatchContainers(t *testing.T) {
+	cli, err := client.NewClientWithOpts(client.FromEnv)
+	require.NoError(t, err)
+
+	dClient := NewClient(cli)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan util.ContainerEvent, 16)
+	go func() {
+		_, _ = dClient.WatchContainers(ctx, ContainerFilter{NameContains: "genericContainer"}, time.Time{}, func(event util.ContainerEvent) {
+			events <- event
+		})
+	}()
+
+	genericContainer, cleanup, err := createGenericContainer(context.Background(), map[string]string{"TEST": "VALUE"})
+	require.NoError(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, util.ContainerStarted, event.Type)
+		assert.Equal(t, genericContainer.Name, event.Container.Name)
+		assert.Equal(t, genericContainer.IP, event.Container.IP)
+		assert.Equal(t, "VALUE", event.Container.Environment["TEST"])
+	case <-time.After(10 * time.Second):
+		t.Fatal("container start wasn't reported")
+	}
+
+	cleanup()
+
+	for {
+		select {
+		case event := <-events:
+			if event.Type == util.ContainerStopped {
+				return
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("container stop wasn't reported")
+		}
+	}
+}
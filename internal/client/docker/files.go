@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+)
+
+// maxReadFileSize bounds files read from containers, they are meant to be small secrets.
+const maxReadFileSize = 64 << 10
+
+// ReadFile reads a regular file from the container through the archive API, so it works without exec.
+func (c *Client) ReadFile(ctx context.Context, containerID, path string) ([]byte, error) {
+	archive, _, err := c.cli.CopyFromContainer(ctx, containerID, path)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("'%s' is not a regular file", path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Size > maxReadFileSize {
+			return nil, fmt.Errorf("'%s' is larger than %d bytes", path, maxReadFileSize)
+		}
+
+		return io.ReadAll(tr)
+	}
+}
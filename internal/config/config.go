@@ -0,0 +1,213 @@
+// Package config holds the settings of every component. They are loaded from defaults, an optional
+// YAML or TOML file, environment variables and command line flags, each overriding the previous ones.
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/minio"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+)
+
+// Settings are named by their YAML keys. The same keys are used in TOML files, flags join them
+// with dots and use dashes instead of camel case, e.g. --discovery.resync-interval, and environment
+// variables are prefixed by OBJECT_STORAGE_, e.g. OBJECT_STORAGE_DISCOVERY_RESYNC_INTERVAL.
+// Settings tagged live can be changed by reloading the config while the gateway runs.
+type Config struct {
+	LogLevel    string            `yaml:"logLevel" toml:"logLevel" live:"true" help:"log level, one of panic, fatal, error, warn, info, debug or trace"`
+	HTTP        HTTPConfig        `yaml:"http" toml:"http"`
+	GRPC        GRPCConfig        `yaml:"grpc" toml:"grpc"`
+	S3          S3Config          `yaml:"s3" toml:"s3"`
+	Tus         TusConfig         `yaml:"tus" toml:"tus"`
+	Discovery   DiscoveryConfig   `yaml:"discovery" toml:"discovery"`
+	Storage     StorageConfig     `yaml:"storage" toml:"storage"`
+	Ring        RingConfig        `yaml:"ring" toml:"ring"`
+	Replication ReplicationConfig `yaml:"replication" toml:"replication"`
+	Rebalance   RebalanceConfig   `yaml:"rebalance" toml:"rebalance"`
+	Readiness   ReadinessConfig   `yaml:"readiness" toml:"readiness"`
+}
+
+type HTTPConfig struct {
+	Address string `yaml:"address" toml:"address" help:"address of the HTTP API"`
+}
+
+type GRPCConfig struct {
+	Address string `yaml:"address" toml:"address" help:"address of the gRPC API, empty disables it"`
+}
+
+// S3Config configures the S3 facade, it's only started when both keys are set.
+type S3Config struct {
+	Address   string `yaml:"address" toml:"address" help:"address of the S3 API"`
+	Bucket    string `yaml:"bucket" toml:"bucket" help:"name of the single bucket served by the S3 API"`
+	Region    string `yaml:"region" toml:"region" help:"region requests to the S3 API are signed for"`
+	AccessKey string `yaml:"accessKey" toml:"accessKey" help:"access key of S3 API clients, empty disables the S3 API"`
+	SecretKey string `yaml:"secretKey" toml:"secretKey" secret:"true" help:"secret key of S3 API clients, empty disables the S3 API"`
+}
+
+type TusConfig struct {
+	BasePath string `yaml:"basePath" toml:"basePath" help:"path tus clients create resumable uploads at"`
+}
+
+type DiscoveryConfig struct {
+	ContainerNameFilter string            `yaml:"containerNameFilter" toml:"containerNameFilter" live:"true" help:"only containers whose name contains it are used as storage nodes"`
+	ContainerLabels     map[string]string `yaml:"containerLabels" toml:"containerLabels" live:"true" help:"labels storage node containers need to have, as key=value pairs separated by commas"`
+	ResyncInterval      time.Duration     `yaml:"resyncInterval" toml:"resyncInterval" live:"true" help:"how often storage nodes are health checked and containers are searched for missed events"`
+	ResubscribeDelay    time.Duration     `yaml:"resubscribeDelay" toml:"resubscribeDelay" live:"true" help:"delay before resubscribing to container events after the stream broke"`
+}
+
+// StorageConfig holds defaults of storage nodes, container labels can override them per node.
+type StorageConfig struct {
+	MinioPort           int           `yaml:"minioPort" toml:"minioPort" help:"port Minio listens on inside storage node containers"`
+	Bucket              string        `yaml:"bucket" toml:"bucket" help:"bucket objects are kept in on storage nodes"`
+	HealthCheckInterval time.Duration `yaml:"healthCheckInterval" toml:"healthCheckInterval" live:"true" help:"interval of Minio client health checks"`
+}
+
+// Storage selectors placing objects on storages.
+const (
+	SelectorConsistentHash = "consistent-hash"
+	SelectorRendezvous     = "rendezvous"
+)
+
+// RingConfig selects how objects are placed, the remaining parameters only apply to the consistent hash ring.
+type RingConfig struct {
+	Selector          string  `yaml:"selector" toml:"selector" help:"placement of objects, consistent-hash or rendezvous"`
+	PartitionCount    int     `yaml:"partitionCount" toml:"partitionCount" live:"true" help:"number of partitions objects are placed by"`
+	ReplicationFactor int     `yaml:"replicationFactor" toml:"replicationFactor" live:"true" help:"points every ring member is hashed to"`
+	Load              float64 `yaml:"load" toml:"load" live:"true" help:"maximum partitions of a member relative to the average"`
+}
+
+type ReplicationConfig struct {
+	Replicas    int `yaml:"replicas" toml:"replicas" help:"number of storage nodes every object is kept on"`
+	WriteQuorum int `yaml:"writeQuorum" toml:"writeQuorum" help:"number of replicas which have to acknowledge a write"`
+}
+
+type RebalanceConfig struct {
+	ObjectsPerSecond int `yaml:"objectsPerSecond" toml:"objectsPerSecond" live:"true" help:"objects copied per second after the ring changed, 0 disables throttling"`
+}
+
+type ReadinessConfig struct {
+	MinReadyNodes int `yaml:"minReadyNodes" toml:"minReadyNodes" help:"healthy storage nodes needed before the gateway reports ready"`
+}
+
+// Default returns the settings used when nothing overrides them.
+func Default() Config {
+	return Config{
+		LogLevel: logrus.InfoLevel.String(),
+		HTTP: HTTPConfig{
+			Address: ":3000",
+		},
+		GRPC: GRPCConfig{
+			Address: ":3002",
+		},
+		S3: S3Config{
+			Address: ":3001",
+			Bucket:  "objects",
+			Region:  "us-east-1",
+		},
+		Tus: TusConfig{
+			BasePath: "/files",
+		},
+		Discovery: DiscoveryConfig{
+			ContainerNameFilter: "amazin-object-storage-node",
+			ContainerLabels:     map[string]string{},
+			ResyncInterval:      30 * time.Second,
+			ResubscribeDelay:    time.Second,
+		},
+		Storage: StorageConfig{
+			MinioPort:           util.DefaultNodeDefaults.Port,
+			Bucket:              util.DefaultNodeDefaults.Bucket,
+			HealthCheckInterval: minio.DefaultHealthCheckInterval,
+		},
+		Ring: RingConfig{
+			Selector:          SelectorConsistentHash,
+			PartitionCount:    util.DefaultPartitionCount,
+			ReplicationFactor: util.DefaultReplicationFactor,
+			Load:              util.DefaultLoad,
+		},
+		// Writes are acknowledged once a majority, both of the two nodes, holds the object, so losing
+		// a single container doesn't lose acknowledged data.
+		Replication: ReplicationConfig{
+			Replicas:    2,
+			WriteQuorum: 2,
+		},
+		Rebalance: RebalanceConfig{
+			ObjectsPerSecond: 50,
+		},
+		Readiness: ReadinessConfig{
+			MinReadyNodes: 1,
+		},
+	}
+}
+
+// S3Enabled reports whether the S3 facade has credentials to serve.
+func (c Config) S3Enabled() bool {
+	return c.S3.AccessKey != "" && c.S3.SecretKey != ""
+}
+
+// Validate returns an error describing every invalid setting.
+func (c Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	_, err := logrus.ParseLevel(c.LogLevel)
+	check(err == nil, "logLevel: unknown level '%s'", c.LogLevel)
+	check(validAddress(c.HTTP.Address), "http.address: invalid address '%s'", c.HTTP.Address)
+	check(c.GRPC.Address == "" || validAddress(c.GRPC.Address), "grpc.address: invalid address '%s'", c.GRPC.Address)
+	check(c.GRPC.Address == "" || c.GRPC.Address != c.HTTP.Address, "grpc.address: has to differ from http.address")
+
+	check((c.S3.AccessKey == "") == (c.S3.SecretKey == ""), "s3: accessKey and secretKey have to be set together")
+	if c.S3Enabled() {
+		check(validAddress(c.S3.Address), "s3.address: invalid address '%s'", c.S3.Address)
+		check(c.S3.Address != c.HTTP.Address && c.S3.Address != c.GRPC.Address, "s3.address: has to differ from http.address and grpc.address")
+		check(c.S3.Bucket != "", "s3.bucket: can't be empty")
+		check(c.S3.Region != "", "s3.region: can't be empty")
+	}
+
+	check(strings.HasPrefix(c.Tus.BasePath, "/") && len(c.Tus.BasePath) > 1 && !strings.HasSuffix(c.Tus.BasePath, "/"),
+		"tus.basePath: has to start and can't end with a slash, got '%s'", c.Tus.BasePath)
+
+	check(c.Discovery.ContainerNameFilter != "" || len(c.Discovery.ContainerLabels) > 0,
+		"discovery: containerNameFilter or containerLabels have to be set, otherwise every container would be used")
+	check(c.Discovery.ResyncInterval > 0, "discovery.resyncInterval: has to be positive")
+	check(c.Discovery.ResubscribeDelay > 0, "discovery.resubscribeDelay: has to be positive")
+
+	check(c.Storage.MinioPort > 0 && c.Storage.MinioPort <= 65535, "storage.minioPort: has to be between 1 and 65535")
+	check(c.Storage.Bucket != "", "storage.bucket: can't be empty")
+	check(c.Storage.HealthCheckInterval > 0, "storage.healthCheckInterval: has to be positive")
+
+	check(c.Ring.Selector == SelectorConsistentHash || c.Ring.Selector == SelectorRendezvous,
+		"ring.selector: has to be %s or %s, got '%s'", SelectorConsistentHash, SelectorRendezvous, c.Ring.Selector)
+	check(c.Ring.PartitionCount > 0, "ring.partitionCount: has to be positive")
+	check(c.Ring.ReplicationFactor > 0, "ring.replicationFactor: has to be positive")
+	check(c.Ring.Load >= 1, "ring.load: has to be at least 1")
+
+	check(c.Replication.Replicas > 0, "replication.replicas: has to be positive")
+	check(c.Replication.WriteQuorum > 0 && c.Replication.WriteQuorum <= c.Replication.Replicas,
+		"replication.writeQuorum: has to be between 1 and replication.replicas")
+
+	check(c.Rebalance.ObjectsPerSecond >= 0, "rebalance.objectsPerSecond: can't be negative")
+	check(c.Readiness.MinReadyNodes >= 0, "readiness.minReadyNodes: can't be negative")
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func validAddress(address string) bool {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 0 && n <= 65535
+}
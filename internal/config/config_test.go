@@ -0,0 +1,240 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func load(args []string, vars map[string]string) (Options, error) {
+	return Load(args, env(vars), io.Discard)
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("when nothing is set, defaults are used and valid", func(t *testing.T) {
+		opts, err := load(nil, nil)
+		require.NoError(t, err)
+		assert.Equal(t, Default(), opts.Config)
+		assert.False(t, opts.PrintConfig)
+		assert.Greater(t, 2*opts.Config.Replication.WriteQuorum, opts.Config.Replication.Replicas, "writes are acknowledged by a majority")
+	})
+
+	t.Run("when a YAML file is given, it overrides defaults", func(t *testing.T) {
+		file := writeFile(t, "config.yaml", `
+http:
+  address: ":4000"
+discovery:
+  resyncInterval: 1m
+  containerLabels:
+    role: storage
+ring:
+  load: 1.5
+`)
+		opts, err := load([]string{"--config", file}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, file, opts.File)
+		assert.Equal(t, ":4000", opts.Config.HTTP.Address)
+		assert.Equal(t, time.Minute, opts.Config.Discovery.ResyncInterval)
+		assert.Equal(t, map[string]string{"role": "storage"}, opts.Config.Discovery.ContainerLabels)
+		assert.Equal(t, 1.5, opts.Config.Ring.Load)
+		assert.Equal(t, Default().GRPC, opts.Config.GRPC)
+	})
+
+	t.Run("when a TOML file is given by the environment, it overrides defaults", func(t *testing.T) {
+		file := writeFile(t, "config.toml", `
+[storage]
+minioPort = 9001
+healthCheckInterval = "5s"
+`)
+		opts, err := load(nil, map[string]string{"OBJECT_STORAGE_CONFIG": file})
+		require.NoError(t, err)
+		assert.Equal(t, 9001, opts.Config.Storage.MinioPort)
+		assert.Equal(t, 5*time.Second, opts.Config.Storage.HealthCheckInterval)
+	})
+
+	t.Run("when a file has unknown keys, it is rejected", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"config.yaml": "http:\n  adress: \":4000\"\n",
+			"config.toml": "[http]\nadress = \":4000\"\n",
+		} {
+			_, err := load([]string{"--config", writeFile(t, name, content)}, nil)
+			assert.Error(t, err, name)
+		}
+	})
+
+	t.Run("when a file has an unknown extension, it is rejected", func(t *testing.T) {
+		_, err := load([]string{"--config", writeFile(t, "config.json", "{}")}, nil)
+		assert.ErrorContains(t, err, "unsupported config file extension")
+	})
+
+	t.Run("flags override environment variables, which override the file", func(t *testing.T) {
+		file := writeFile(t, "config.yaml", "replication:\n  replicas: 3\n  writeQuorum: 3\n")
+		vars := map[string]string{
+			"OBJECT_STORAGE_REPLICATION_WRITE_QUORUM": "2",
+			"OBJECT_STORAGE_LOG_LEVEL":                "debug",
+		}
+
+		opts, err := load([]string{"--config", file, "--log-level", "warn"}, vars)
+		require.NoError(t, err)
+		assert.Equal(t, 3, opts.Config.Replication.Replicas)
+		assert.Equal(t, 2, opts.Config.Replication.WriteQuorum)
+		assert.Equal(t, "warn", opts.Config.LogLevel)
+	})
+
+	t.Run("when S3 credentials are set by the environment, S3 API is enabled", func(t *testing.T) {
+		opts, err := load(nil, map[string]string{
+			"OBJECT_STORAGE_S3_ACCESS_KEY": "access",
+			"OBJECT_STORAGE_S3_SECRET_KEY": "secret",
+		})
+		require.NoError(t, err)
+		assert.True(t, opts.Config.S3Enabled())
+	})
+
+	t.Run("when a variable is set but empty, it clears the setting", func(t *testing.T) {
+		opts, err := load(nil, map[string]string{"OBJECT_STORAGE_GRPC_ADDRESS": ""})
+		require.NoError(t, err)
+		assert.Empty(t, opts.Config.GRPC.Address)
+	})
+
+	t.Run("when labels are given by a flag, they are parsed as pairs", func(t *testing.T) {
+		opts, err := load([]string{"--discovery.container-labels", "role=storage, managed"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"role": "storage", "managed": ""}, opts.Config.Discovery.ContainerLabels)
+	})
+
+	t.Run("when a value can't be parsed, its source is named", func(t *testing.T) {
+		_, err := load(nil, map[string]string{"OBJECT_STORAGE_DISCOVERY_RESYNC_INTERVAL": "often"})
+		assert.ErrorContains(t, err, "OBJECT_STORAGE_DISCOVERY_RESYNC_INTERVAL")
+
+		_, err = load([]string{"--ring.partition-count", "many"}, nil)
+		assert.ErrorContains(t, err, "--ring.partition-count")
+	})
+
+	t.Run("when the result is invalid, every problem is reported", func(t *testing.T) {
+		_, err := load([]string{"--replication.write-quorum", "3", "--storage.minio-port", "0"}, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "replication.writeQuorum")
+		assert.Contains(t, err.Error(), "storage.minioPort")
+	})
+
+	t.Run("when print-config is set, it is reported", func(t *testing.T) {
+		opts, err := load([]string{"--print-config"}, nil)
+		require.NoError(t, err)
+		assert.True(t, opts.PrintConfig)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	for name, modify := range map[string]func(c *Config){
+		"unknown log level":          func(c *Config) { c.LogLevel = "loud" },
+		"invalid http address":       func(c *Config) { c.HTTP.Address = "3000" },
+		"grpc on the http address":   func(c *Config) { c.GRPC.Address = c.HTTP.Address },
+		"only one S3 key":            func(c *Config) { c.S3.AccessKey = "access" },
+		"tus path with a slash":      func(c *Config) { c.Tus.BasePath = "/files/" },
+		"no discovery filter":        func(c *Config) { c.Discovery.ContainerNameFilter = "" },
+		"zero resync interval":       func(c *Config) { c.Discovery.ResyncInterval = 0 },
+		"empty bucket":               func(c *Config) { c.Storage.Bucket = "" },
+		"unknown selector":           func(c *Config) { c.Ring.Selector = "hrw" },
+		"load below one":             func(c *Config) { c.Ring.Load = 0.5 },
+		"no replicas":                func(c *Config) { c.Replication.Replicas = 0 },
+		"negative rebalance rate":    func(c *Config) { c.Rebalance.ObjectsPerSecond = -1 },
+		"negative ready node number": func(c *Config) { c.Readiness.MinReadyNodes = -1 },
+	} {
+		t.Run("when "+name+" is set, config is invalid", func(t *testing.T) {
+			cfg := Default()
+			modify(&cfg)
+			assert.Error(t, cfg.Validate())
+		})
+	}
+
+	t.Run("when rendezvous selector is set, config is valid", func(t *testing.T) {
+		cfg := Default()
+		cfg.Ring.Selector = SelectorRendezvous
+		assert.NoError(t, cfg.Validate())
+	})
+
+	t.Run("when gRPC is disabled, config is valid", func(t *testing.T) {
+		cfg := Default()
+		cfg.GRPC.Address = ""
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestPrint(t *testing.T) {
+	cfg := Default()
+	cfg.S3.AccessKey = "access"
+	cfg.S3.SecretKey = "secret"
+
+	var out bytes.Buffer
+	require.NoError(t, Print(&out, cfg))
+	assert.Contains(t, out.String(), "accessKey: access")
+	assert.Contains(t, out.String(), "secretKey: <redacted>")
+	assert.NotContains(t, out.String(), "secret\n")
+	assert.Equal(t, "secret", cfg.S3.SecretKey, "printing doesn't change the config")
+
+	t.Run("printed config can be loaded back", func(t *testing.T) {
+		cfg := Default()
+		cfg.Ring.PartitionCount = 13
+		var out bytes.Buffer
+		require.NoError(t, Print(&out, cfg))
+
+		opts, err := load([]string{"--config", writeFile(t, "config.yaml", out.String())}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, cfg, opts.Config)
+	})
+}
+
+func TestReload(t *testing.T) {
+	current := Default()
+
+	t.Run("when live settings change, they are applied", func(t *testing.T) {
+		next := Default()
+		next.LogLevel = "debug"
+		next.Discovery.ContainerLabels = map[string]string{"role": "storage"}
+		next.Ring.PartitionCount = 13
+
+		reloaded, rejected := current.Reload(next)
+		assert.Empty(t, rejected)
+		assert.Equal(t, next, reloaded)
+		assert.Equal(t, []string{"logLevel", "discovery.containerLabels", "ring.partitionCount"}, current.Changes(reloaded))
+	})
+
+	t.Run("when other settings change, they are rejected and kept", func(t *testing.T) {
+		next := Default()
+		next.HTTP.Address = ":4000"
+		next.Replication.Replicas = 3
+		next.Rebalance.ObjectsPerSecond = 10
+
+		reloaded, rejected := current.Reload(next)
+		assert.Equal(t, []string{"http.address", "replication.replicas"}, rejected)
+		assert.Equal(t, current.HTTP, reloaded.HTTP)
+		assert.Equal(t, current.Replication, reloaded.Replication)
+		assert.Equal(t, 10, reloaded.Rebalance.ObjectsPerSecond)
+	})
+
+	t.Run("when labels are missing instead of empty, nothing changed", func(t *testing.T) {
+		next := Default()
+		next.Discovery.ContainerLabels = nil
+
+		assert.Empty(t, current.Changes(next))
+	})
+}
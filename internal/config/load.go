@@ -0,0 +1,337 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix     = "OBJECT_STORAGE_"
+	configFileEnv = envPrefix + "CONFIG"
+)
+
+// Options is the result of parsing the command line.
+type Options struct {
+	Config Config
+	// File is the config file the settings were read from, if any.
+	File string
+	// PrintConfig asks for the effective config to be printed instead of starting the gateway.
+	PrintConfig bool
+}
+
+// Load builds the config from defaults, the config file, environment variables and args, in increasing
+// order of precedence. The config file is named by the --config flag or the OBJECT_STORAGE_CONFIG variable.
+// Variables are looked up with lookupEnv, set but empty ones apply too, so they can clear settings.
+// flag.ErrHelp is returned when args ask for usage, which is then already written to output.
+func Load(args []string, lookupEnv func(string) (string, bool), output io.Writer) (Options, error) {
+	fs := flag.NewFlagSet("homework-object-storage", flag.ContinueOnError)
+	fs.SetOutput(output)
+	defaultFile, _ := lookupEnv(configFileEnv)
+	file := fs.String("config", defaultFile, "YAML or TOML config file, also read from "+configFileEnv)
+	printConfig := fs.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+
+	defaults := Default()
+	flagValues := map[string]string{}
+	for _, s := range settings() {
+		fs.Var(&rawFlag{
+			name:   s.flagName(),
+			value:  formatValue(s.value(&defaults)),
+			isBool: s.kind == reflect.Bool,
+			values: flagValues,
+		}, s.flagName(), s.help)
+	}
+	if err := fs.Parse(args); err != nil {
+		return Options{}, err
+	}
+	if fs.NArg() > 0 {
+		return Options{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg, err := loadConfig(*file, lookupEnv, flagValues)
+	if err != nil {
+		return Options{}, err
+	}
+	return Options{
+		Config:      cfg,
+		File:        *file,
+		PrintConfig: *printConfig,
+	}, nil
+}
+
+func loadConfig(file string, lookupEnv func(string) (string, bool), flagValues map[string]string) (Config, error) {
+	cfg := Default()
+	if file != "" {
+		if err := readFile(file, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings() {
+		raw, ok := lookupEnv(s.envName())
+		if !ok {
+			continue
+		}
+		if err := setValue(s.value(&cfg), raw); err != nil {
+			return Config{}, fmt.Errorf("invalid value of %s: %w", s.envName(), err)
+		}
+	}
+
+	for _, s := range settings() {
+		raw, ok := flagValues[s.flagName()]
+		if !ok {
+			continue
+		}
+		if err := setValue(s.value(&cfg), raw); err != nil {
+			return Config{}, fmt.Errorf("invalid value of --%s: %w", s.flagName(), err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// readFile decodes the file over cfg, so settings missing in the file keep their current values.
+// Unknown keys are rejected, they're most likely typos.
+func readFile(file string, cfg *Config) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("couldn't read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("couldn't parse config file %s: %w", file, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("couldn't parse config file %s: %w", file, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return fmt.Errorf("couldn't parse config file %s: unknown keys %s", file, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension '%s', use .yaml, .yml or .toml", ext)
+	}
+	return nil
+}
+
+// Print writes cfg as YAML, with secrets redacted.
+func Print(w io.Writer, cfg Config) error {
+	for _, s := range settings() {
+		if v := s.value(&cfg); s.secret && v.String() != "" {
+			v.SetString("<redacted>")
+		}
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(cfg); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// setting is a single leaf of Config.
+type setting struct {
+	path   []string
+	index  []int
+	kind   reflect.Kind
+	help   string
+	secret bool
+	live   bool
+}
+
+func settings() []setting {
+	var result []setting
+	var walk func(t reflect.Type, path []string, index []int)
+	walk = func(t reflect.Type, path []string, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldPath := append(append([]string{}, path...), field.Tag.Get("yaml"))
+			fieldIndex := append(append([]int{}, index...), i)
+			if field.Type.Kind() == reflect.Struct {
+				walk(field.Type, fieldPath, fieldIndex)
+				continue
+			}
+			result = append(result, setting{
+				path:   fieldPath,
+				index:  fieldIndex,
+				kind:   field.Type.Kind(),
+				help:   field.Tag.Get("help"),
+				secret: field.Tag.Get("secret") == "true",
+				live:   field.Tag.Get("live") == "true",
+			})
+		}
+	}
+	walk(reflect.TypeOf(Config{}), nil, nil)
+	return result
+}
+
+func (s setting) value(cfg *Config) reflect.Value {
+	return reflect.ValueOf(cfg).Elem().FieldByIndex(s.index)
+}
+
+// name returns the setting's path of YAML keys, e.g. discovery.resyncInterval.
+func (s setting) name() string {
+	return strings.Join(s.path, ".")
+}
+
+// flagName returns e.g. discovery.resync-interval for discovery.resyncInterval.
+func (s setting) flagName() string {
+	parts := make([]string, len(s.path))
+	for i, key := range s.path {
+		parts[i] = strings.Join(splitWords(key), "-")
+	}
+	return strings.Join(parts, ".")
+}
+
+// envName returns e.g. OBJECT_STORAGE_DISCOVERY_RESYNC_INTERVAL for discovery.resyncInterval.
+func (s setting) envName() string {
+	var words []string
+	for _, key := range s.path {
+		words = append(words, splitWords(key)...)
+	}
+	return envPrefix + strings.ToUpper(strings.Join(words, "_"))
+}
+
+// splitWords splits a camel case key into lower case words.
+func splitWords(key string) []string {
+	var words []string
+	start := 0
+	for i, r := range key {
+		if i > 0 && unicode.IsUpper(r) {
+			words = append(words, strings.ToLower(key[start:i]))
+			start = i
+		}
+	}
+	return append(words, strings.ToLower(key[start:]))
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Map:
+		m, err := parseMap(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// parseMap parses key=value pairs separated by commas, a key without a value maps to an empty string.
+func parseMap(raw string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value := pair, ""
+		if i := strings.Index(pair, "="); i >= 0 {
+			key, value = pair[:i], pair[i+1:]
+		}
+		if key == "" {
+			return nil, fmt.Errorf("expected key=value, got '%s'", pair)
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+func formatValue(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Kind() == reflect.Map {
+		pairs := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", key, v.MapIndex(key)))
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// rawFlag records the flag's value as given, so it can be applied after the config file and
+// environment variables, which are only known once the flags are parsed.
+type rawFlag struct {
+	name   string
+	value  string
+	isBool bool
+	values map[string]string
+}
+
+func (f *rawFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *rawFlag) Set(raw string) error {
+	f.value = raw
+	f.values[f.name] = raw
+	return nil
+}
+
+func (f *rawFlag) IsBoolFlag() bool {
+	return f.isBool
+}
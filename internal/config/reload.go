@@ -0,0 +1,38 @@
+package config
+
+import "reflect"
+
+// Reload returns next with every setting which can't change while the gateway runs kept at its
+// value in c, along with names of those settings whose change is therefore rejected.
+func (c Config) Reload(next Config) (Config, []string) {
+	var rejected []string
+	for _, s := range settings() {
+		if s.live {
+			continue
+		}
+		if current, value := s.value(&c), s.value(&next); !equal(current, value) {
+			rejected = append(rejected, s.name())
+			value.Set(current)
+		}
+	}
+	return next, rejected
+}
+
+// Changes returns names of settings whose values differ between c and next.
+func (c Config) Changes(next Config) []string {
+	var changed []string
+	for _, s := range settings() {
+		if !equal(s.value(&c), s.value(&next)) {
+			changed = append(changed, s.name())
+		}
+	}
+	return changed
+}
+
+// equal compares setting values, a missing map is the same as an empty one.
+func equal(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Map && a.Len() == 0 && b.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
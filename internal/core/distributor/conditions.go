@@ -0,0 +1,96 @@
+package distributor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+)
+
+// anyETag matches any existing object in PutOptions.
+const anyETag = "*"
+
+type PutOptions struct {
+	Metadata core.ObjectMetadata
+	// IfMatch lists ETags of which the object has to currently have one, "*" accepts any existing object.
+	IfMatch []string
+	// IfNoneMatch lists ETags the object must not currently have, "*" only accepts a missing object.
+	IfNoneMatch []string
+}
+
+// conditional reports whether the write depends on the current state of the object.
+func (o PutOptions) conditional() bool {
+	return len(o.IfMatch) > 0 || len(o.IfNoneMatch) > 0
+}
+
+// checkPreconditions compares the newest copy seen by a quorum of replicas with the write conditions.
+// Conditional writes of an object are serialized by the caller, so the check and the write don't race
+// with other conditional writes going through this distributor.
+func (d *ObjectDistributor) checkPreconditions(ctx context.Context, objectID string, opts PutOptions) error {
+	exists := true
+	info, err := d.StatObject(ctx, objectID, GetOptions{Consistency: ConsistencyQuorum})
+	switch err {
+	case nil:
+	// Ok
+	case core.ErrNotFound:
+		exists = false
+	default:
+		return err
+	}
+
+	if len(opts.IfMatch) > 0 && (!exists || !matchesETag(opts.IfMatch, info.ETag)) {
+		return core.ErrPreconditionFailed
+	}
+	if len(opts.IfNoneMatch) > 0 && exists && matchesETag(opts.IfNoneMatch, info.ETag) {
+		return core.ErrPreconditionFailed
+	}
+	return nil
+}
+
+func matchesETag(etags []string, etag string) bool {
+	for _, candidate := range etags {
+		if candidate == anyETag || (etag != "" && candidate == etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// objectLocks hands out a mutex per object ID, dropping it once nobody holds or waits for it.
+// The locks are in-process only: two gateways in front of the same storages can both pass a precondition
+// and both write, so If-None-Match: * only guarantees create-only writes with a single gateway.
+type objectLocks struct {
+	l     sync.Mutex
+	locks map[string]*objectLock
+}
+
+type objectLock struct {
+	sync.Mutex
+	refs int
+}
+
+func (o *objectLocks) lock(objectID string) func() {
+	o.l.Lock()
+	if o.locks == nil {
+		o.locks = make(map[string]*objectLock)
+	}
+	lock, ok := o.locks[objectID]
+	if !ok {
+		lock = &objectLock{}
+		o.locks[objectID] = lock
+	}
+	lock.refs++
+	o.l.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		o.l.Lock()
+		defer o.l.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(o.locks, objectID)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package distributor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func putConditionally(distributor *ObjectDistributor, objectID string, blob []byte, opts PutOptions) error {
+	_, err := distributor.PutObject(context.TODO(), objectID, bytes.NewReader(blob), int64(len(blob)), opts)
+	return err
+}
+
+func TestObjectDistributorConditionalPut(t *testing.T) {
+	const objectID = "object_id"
+
+	distributor := NewObjectDistributor(newMemoryStorageSelector(), WithReplication(2, 2))
+	for i := 0; i < 3; i++ {
+		distributor.AddStorage(fmt.Sprint(i), memory.NewObjectStorage(), 1)
+	}
+
+	t.Run("when object is missing, create-only put succeeds once", func(t *testing.T) {
+		createOnly := PutOptions{IfNoneMatch: []string{anyETag}}
+		require.NoError(t, putConditionally(distributor, objectID, []byte("v1"), createOnly))
+
+		err := putConditionally(distributor, objectID, []byte("v2"), createOnly)
+		assert.Equal(t, core.ErrPreconditionFailed, err)
+
+		blob, err := getObject(distributor, objectID)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v1"), blob)
+	})
+
+	t.Run("when ETag matches, object is swapped", func(t *testing.T) {
+		info, err := distributor.StatObject(context.TODO(), objectID, GetOptions{})
+		require.NoError(t, err)
+
+		require.NoError(t, putConditionally(distributor, objectID, []byte("v2"), PutOptions{IfMatch: []string{info.ETag}}))
+
+		err = putConditionally(distributor, objectID, []byte("v3"), PutOptions{IfMatch: []string{info.ETag}})
+		assert.Equal(t, core.ErrPreconditionFailed, err)
+
+		blob, err := getObject(distributor, objectID)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("v2"), blob)
+	})
+
+	t.Run("when object is missing, If-Match fails", func(t *testing.T) {
+		err := putConditionally(distributor, "missing", []byte("v1"), PutOptions{IfMatch: []string{anyETag}})
+		assert.Equal(t, core.ErrPreconditionFailed, err)
+	})
+
+	t.Run("when concurrent swaps race, only one wins", func(t *testing.T) {
+		info, err := distributor.StatObject(context.TODO(), objectID, GetOptions{})
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		results := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results <- putConditionally(distributor, objectID, []byte(fmt.Sprintf("v3_%d", i)), PutOptions{IfMatch: []string{info.ETag}})
+			}(i)
+		}
+		wg.Wait()
+		close(results)
+
+		succeeded := 0
+		for err := range results {
+			if err == nil {
+				succeeded++
+			} else {
+				assert.Equal(t, core.ErrPreconditionFailed, err)
+			}
+		}
+		assert.Equal(t, 1, succeeded)
+	})
+
+	distributor.repairs.Wait()
+}
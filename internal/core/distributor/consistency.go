@@ -0,0 +1,256 @@
+package distributor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/metrics"
+)
+
+// ConsistencyLevel says how many replicas have to answer a read before it is served.
+type ConsistencyLevel int
+
+const (
+	// ConsistencyOne serves the first replica holding the object.
+	ConsistencyOne ConsistencyLevel = iota
+	// ConsistencyQuorum compares a majority of replicas and serves the newest copy.
+	ConsistencyQuorum
+	// ConsistencyAll compares every replica and serves the newest copy.
+	ConsistencyAll
+)
+
+const repairTimeout = 5 * time.Minute
+
+type GetOptions struct {
+	Consistency ConsistencyLevel
+	// Range limits the returned body, the returned info still describes the whole object.
+	Range *core.ByteRange
+	// MatchETag pins the read to a version of the object, other versions fail with core.ErrPreconditionFailed.
+	MatchETag string
+}
+
+func (o GetOptions) storageOptions() core.GetOptions {
+	return core.GetOptions{Range: o.Range, MatchETag: o.MatchETag}
+}
+
+func ParseConsistencyLevel(level string) (ConsistencyLevel, error) {
+	switch strings.ToLower(level) {
+	case "", "one":
+		return ConsistencyOne, nil
+	case "quorum":
+		return ConsistencyQuorum, nil
+	case "all":
+		return ConsistencyAll, nil
+	default:
+		return ConsistencyOne, fmt.Errorf("unknown consistency level '%s'", level)
+	}
+}
+
+func (c ConsistencyLevel) String() string {
+	switch c {
+	case ConsistencyQuorum:
+		return "quorum"
+	case ConsistencyAll:
+		return "all"
+	default:
+		return "one"
+	}
+}
+
+func (c ConsistencyLevel) requiredReplicas(replicas int) int {
+	switch c {
+	case ConsistencyQuorum:
+		return replicas/2 + 1
+	case ConsistencyAll:
+		return replicas
+	default:
+		return 1
+	}
+}
+
+type replicaStat struct {
+	replica replica
+	info    core.ObjectInfo
+	err     error
+}
+
+// locateNewest finds the replica holding the newest copy of the object and the replicas which are behind it.
+func (d *ObjectDistributor) locateNewest(ctx context.Context, objectID string, replicas []replica, level ConsistencyLevel) (replica, core.ObjectInfo, []replica, error) {
+	if level == ConsistencyOne {
+		return d.locateFirst(ctx, objectID, replicas)
+	}
+
+	results := make(chan replicaStat, len(replicas))
+	for _, rep := range replicas {
+		go func(rep replica) {
+			info, err := rep.storage.Stat(ctx, objectID)
+			results <- replicaStat{replica: rep, info: info, err: err}
+		}(rep)
+	}
+
+	stats := make(map[string]replicaStat, len(replicas))
+	responded := 0
+	var lastErr error
+	for range replicas {
+		res := <-results
+		switch res.err {
+		case nil, core.ErrNotFound:
+			stats[res.replica.id] = res
+			responded++
+		default:
+			logReplicaError(objectID, res.replica.id, res.err, "getting object info")
+			lastErr = res.err
+		}
+	}
+
+	required := level.requiredReplicas(len(replicas))
+	if responded < required {
+		return replica{}, core.ObjectInfo{}, nil, fmt.Errorf("%w: %d of %d replicas responded, %d required: %v", core.ErrQuorumNotReached, responded, len(replicas), required, lastErr)
+	}
+
+	// Replicas are walked in ownership order, so on equal modification times the primary owner wins.
+	var newest *replicaStat
+	for _, rep := range replicas {
+		stat, ok := stats[rep.id]
+		if !ok || stat.err != nil {
+			continue
+		}
+		if newest == nil || stat.info.LastModified.After(newest.info.LastModified) {
+			stat := stat
+			newest = &stat
+		}
+	}
+	if newest == nil {
+		return replica{}, core.ObjectInfo{}, nil, core.ErrNotFound
+	}
+
+	stale := make([]replica, 0)
+	for _, rep := range replicas {
+		stat, ok := stats[rep.id]
+		if !ok || rep.id == newest.replica.id {
+			continue
+		}
+		if stat.err == core.ErrNotFound || stat.info.ETag != newest.info.ETag {
+			stale = append(stale, rep)
+		}
+	}
+	return newest.replica, newest.info, stale, nil
+}
+
+// locateFirst returns the first replica holding the object, replicas before it which miss the object are stale.
+func (d *ObjectDistributor) locateFirst(ctx context.Context, objectID string, replicas []replica) (replica, core.ObjectInfo, []replica, error) {
+	lastErr := core.ErrNotFound
+	stale := make([]replica, 0)
+	for _, rep := range replicas {
+		info, err := rep.storage.Stat(ctx, objectID)
+		switch err {
+		case nil:
+			return rep, info, stale, nil
+		case core.ErrNotFound:
+			stale = append(stale, rep)
+		default:
+			logReplicaError(objectID, rep.id, err, "getting object info")
+			lastErr = err
+		}
+	}
+	return replica{}, core.ObjectInfo{}, nil, lastErr
+}
+
+// getFirst serves the first replica holding the object without comparing it to the others.
+func (d *ObjectDistributor) getFirst(ctx context.Context, objectID string, replicas []replica, opts core.GetOptions) (io.ReadCloser, core.ObjectInfo, error) {
+	lastErr := core.ErrNotFound
+	stale := make([]replica, 0)
+	for _, rep := range replicas {
+		body, info, err := rep.storage.Get(ctx, objectID, opts)
+		switch err {
+		case nil:
+			traceSource(ctx, rep)
+			d.repair(objectID, rep, info, stale)
+			return body, info, nil
+		case core.ErrNotFound:
+			stale = append(stale, rep)
+		case core.ErrPreconditionFailed:
+			// Another replica may still hold the requested version.
+			lastErr = err
+		default:
+			logReplicaError(objectID, rep.id, err, "getting object")
+			lastErr = err
+		}
+	}
+	return nil, core.ObjectInfo{}, lastErr
+}
+
+// repair asynchronously copies the object from source to the stale replicas.
+func (d *ObjectDistributor) repair(objectID string, source replica, sourceInfo core.ObjectInfo, stale []replica) {
+	if len(stale) == 0 {
+		return
+	}
+
+	d.l.RLock()
+	defer d.l.RUnlock()
+	if d.closed {
+		return
+	}
+	d.repairs.Add(1)
+	go func() {
+		defer d.repairs.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), repairTimeout)
+		defer cancel()
+
+		for _, target := range stale {
+			copied, err := d.copyReplica(ctx, objectID, source, sourceInfo, target)
+			if err != nil {
+				metrics.ObserveRepair(err)
+				logReplicaError(objectID, target.id, err, "repairing replica")
+				continue
+			}
+			if !copied {
+				continue
+			}
+			metrics.ObserveRepair(nil)
+			logrus.WithFields(logrus.Fields{
+				"id":        objectID,
+				"storageID": target.id,
+				"sourceID":  source.id,
+			}).Info("repaired replica")
+		}
+	}()
+}
+
+// Close waits for running repairs to finish, reads don't start new ones afterwards.
+func (d *ObjectDistributor) Close() {
+	d.l.Lock()
+	d.closed = true
+	d.l.Unlock()
+
+	d.repairs.Wait()
+}
+
+// copyReplica copies the object from source to target unless the target already holds the same or a newer copy.
+func (d *ObjectDistributor) copyReplica(ctx context.Context, objectID string, source replica, sourceInfo core.ObjectInfo, target replica) (bool, error) {
+	// The target could have been written since it was found stale, never replace a newer copy.
+	targetInfo, err := target.storage.Stat(ctx, objectID)
+	if err == nil && (targetInfo.ETag == sourceInfo.ETag || targetInfo.LastModified.After(sourceInfo.LastModified)) {
+		return false, nil
+	}
+	if err != nil && err != core.ErrNotFound {
+		return false, err
+	}
+
+	body, info, err := source.storage.Get(ctx, objectID, core.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("getting object from '%s' storage: %w", source.id, err)
+	}
+	defer body.Close()
+
+	if _, err := target.storage.Put(ctx, objectID, body, info.Size, info.Metadata); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,128 @@
+package distributor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readStorage(t *testing.T, storage ObjectStorage, objectID string) []byte {
+	body, _, err := storage.Get(context.TODO(), objectID, core.GetOptions{})
+	require.NoError(t, err)
+	defer body.Close()
+
+	blob, err := io.ReadAll(body)
+	require.NoError(t, err)
+	return blob
+}
+
+func newReplicatedDistributor(storages ...ObjectStorage) *ObjectDistributor {
+	distributor := NewObjectDistributor(newMemoryStorageSelector(), WithReplication(len(storages), len(storages)))
+	for i, storage := range storages {
+		distributor.AddStorage(string(rune('1'+i)), storage, 1)
+	}
+	return distributor
+}
+
+func TestObjectDistributorConsistency(t *testing.T) {
+	const objectID = "object_id"
+
+	t.Run("when replica holds newer copy, quorum read serves it and repairs the others", func(t *testing.T) {
+		storages := []*memory.ObjectStorage{memory.NewObjectStorage(), memory.NewObjectStorage(), memory.NewObjectStorage()}
+		distributor := newReplicatedDistributor(storages[0], storages[1], storages[2])
+
+		require.NoError(t, putObject(distributor, objectID, []byte("old")))
+
+		time.Sleep(time.Millisecond)
+		newer := []byte("new")
+		_, err := storages[2].Put(context.TODO(), objectID, bytes.NewReader(newer), int64(len(newer)), core.ObjectMetadata{})
+		require.NoError(t, err)
+
+		body, _, err := distributor.GetObject(context.TODO(), objectID, GetOptions{Consistency: ConsistencyQuorum})
+		require.NoError(t, err)
+		actualObject, err := io.ReadAll(body)
+		require.NoError(t, err)
+		body.Close()
+		assert.Equal(t, newer, actualObject)
+
+		distributor.repairs.Wait()
+		for _, storage := range storages {
+			assert.Equal(t, newer, readStorage(t, storage, objectID))
+		}
+	})
+
+	t.Run("when replica misses the object, read repairs it", func(t *testing.T) {
+		storages := []*memory.ObjectStorage{memory.NewObjectStorage(), memory.NewObjectStorage(), memory.NewObjectStorage()}
+		distributor := newReplicatedDistributor(storages[0], storages[1], storages[2])
+
+		blob := []byte("Hello")
+		require.NoError(t, putObject(distributor, objectID, blob))
+		require.NoError(t, storages[1].Delete(context.TODO(), objectID))
+
+		info, err := distributor.StatObject(context.TODO(), objectID, GetOptions{Consistency: ConsistencyAll})
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(blob)), info.Size)
+
+		distributor.repairs.Wait()
+		assert.Equal(t, blob, readStorage(t, storages[1], objectID))
+
+		t.Run("when distributor is closed, reads don't repair anymore", func(t *testing.T) {
+			require.NoError(t, storages[1].Delete(context.TODO(), objectID))
+			distributor.Close()
+
+			_, err := distributor.StatObject(context.TODO(), objectID, GetOptions{Consistency: ConsistencyAll})
+			require.NoError(t, err)
+			distributor.repairs.Wait()
+			_, err = storages[1].Stat(context.TODO(), objectID)
+			assert.Equal(t, core.ErrNotFound, err)
+		})
+	})
+
+	t.Run("when too few replicas respond, quorum read fails but read of one succeeds", func(t *testing.T) {
+		storage := memory.NewObjectStorage()
+		distributor := NewObjectDistributor(newMemoryStorageSelector(), WithReplication(3, 1))
+		distributor.AddStorage("1", storage, 1)
+		distributor.AddStorage("2", failingStorage{}, 1)
+		distributor.AddStorage("3", failingStorage{}, 1)
+
+		blob := []byte("Hello")
+		require.NoError(t, putObject(distributor, objectID, blob))
+
+		_, _, err := distributor.GetObject(context.TODO(), objectID, GetOptions{Consistency: ConsistencyQuorum})
+		assert.ErrorIs(t, err, core.ErrQuorumNotReached)
+
+		actualObject, err := getObject(distributor, objectID)
+		assert.NoError(t, err)
+		assert.Equal(t, blob, actualObject)
+	})
+
+	t.Run("when no replica has the object, we should return not found", func(t *testing.T) {
+		distributor := newReplicatedDistributor(memory.NewObjectStorage(), memory.NewObjectStorage())
+
+		_, _, err := distributor.GetObject(context.TODO(), "random_object_id", GetOptions{Consistency: ConsistencyAll})
+		assert.Equal(t, core.ErrNotFound, err)
+	})
+}
+
+func TestParseConsistencyLevel(t *testing.T) {
+	for input, expected := range map[string]ConsistencyLevel{
+		"":       ConsistencyOne,
+		"one":    ConsistencyOne,
+		"QUORUM": ConsistencyQuorum,
+		"all":    ConsistencyAll,
+	} {
+		level, err := ParseConsistencyLevel(input)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, level)
+	}
+
+	_, err := ParseConsistencyLevel("some")
+	assert.Error(t, err)
+}
@@ -3,76 +3,393 @@ package distributor
 import (
 	"context"
 	"fmt"
+	"io"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/metrics"
+	"github.com/spacelift-io/homework-object-storage/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ObjectDistributor struct {
 	storages        map[string]ObjectStorage
 	storageSelector StorageSelector
+	replicas        int
+	writeQuorum     int
+	migrateFallback bool
 	l               sync.RWMutex
+
+	ringChangeFns []RingChangeFn
+	repairs       sync.WaitGroup
+	// closed stops new repairs from starting, so waiting for the running ones ends.
+	closed      bool
+	objectLocks objectLocks
 }
 
 type ObjectStorage interface {
-	Put(ctx context.Context, objectID string, blob []byte) error
-	Get(ctx context.Context, objectID string) ([]byte, error)
+	// Put stores the object read from r along with its metadata and returns the stored object's info.
+	// Size is -1 when it isn't known upfront. LastModified is zero when the storage doesn't report it.
+	Put(ctx context.Context, objectID string, r io.Reader, size int64, metadata core.ObjectMetadata) (core.ObjectInfo, error)
+	// Get returns the object body, which has to be closed by the caller.
+	Get(ctx context.Context, objectID string, opts core.GetOptions) (io.ReadCloser, core.ObjectInfo, error)
+	Stat(ctx context.Context, objectID string) (core.ObjectInfo, error)
+	Delete(ctx context.Context, objectID string) error
+	List(ctx context.Context, opts core.ListOptions) ([]core.ObjectInfo, error)
+}
+
+// Ring is a read-only view of object placement.
+type Ring interface {
+	// Version is incremented on every membership change.
+	Version() uint64
+	// LocateStorages returns up to count distinct storages owning the object, the primary owner first.
+	LocateStorages(objectID string, count int) []string
+}
+
+// PartitionedRing is a Ring which places objects by a fixed set of partitions,
+// so two rings can be compared partition by partition instead of object by object.
+type PartitionedRing interface {
+	Ring
+	PartitionCount() int
+	LocatePartition(objectID string) int
+	PartitionOwners(partitionID, count int) []string
 }
 
 type StorageSelector interface {
-	AddStorage(storageID string)
+	Ring
+	// AddStorage adds the storage or updates its weight. Weight is the relative share of objects
+	// the storage should own, selectors which can't weight storages ignore it.
+	AddStorage(storageID string, weight float64)
 	RemoveStorage(storageID string)
 	LocateStorage(objectID string) string
+	// Snapshot returns the current placement, unaffected by later membership changes.
+	Snapshot() Ring
+	// Previous returns the placement from before the last membership change, nil if there was none.
+	Previous() Ring
+}
+
+// ZonedStorageSelector is a StorageSelector spreading the owners of every object across as many zones as possible.
+type ZonedStorageSelector interface {
+	StorageSelector
+	// AddZonedStorage adds the storage like AddStorage, placing it in the zone. Storages without a zone share one.
+	AddZonedStorage(storageID string, weight float64, zone string)
+}
+
+// RingChangeFn is called after storage membership changed with the placement before and after the change.
+type RingChangeFn func(previous, current Ring)
+
+type Option func(d *ObjectDistributor)
+
+// WithReplication stores every object on replicas storages and treats a write as successful
+// once writeQuorum of them acknowledged it. A writeQuorum outside 1..replicas falls back to a majority.
+func WithReplication(replicas, writeQuorum int) Option {
+	return func(d *ObjectDistributor) {
+		if replicas < 1 {
+			replicas = 1
+		}
+		if writeQuorum < 1 || writeQuorum > replicas {
+			writeQuorum = replicas/2 + 1
+		}
+		d.replicas = replicas
+		d.writeQuorum = writeQuorum
+	}
 }
 
-func NewObjectDistributor(storageSelector StorageSelector) *ObjectDistributor {
-	return &ObjectDistributor{
+// WithFallbackMigration copies objects found only on their previous owners to their current owners.
+func WithFallbackMigration() Option {
+	return func(d *ObjectDistributor) {
+		d.migrateFallback = true
+	}
+}
+
+func NewObjectDistributor(storageSelector StorageSelector, opts ...Option) *ObjectDistributor {
+	d := &ObjectDistributor{
 		storages:        make(map[string]ObjectStorage),
 		storageSelector: storageSelector,
+		replicas:        1,
+		writeQuorum:     1,
 	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
 }
 
-func (d *ObjectDistributor) AddStorage(storageID string, storage ObjectStorage) {
+// OnRingChange registers fn to be called after every storage membership change.
+func (d *ObjectDistributor) OnRingChange(fn RingChangeFn) {
 	d.l.Lock()
 	defer d.l.Unlock()
 
-	d.storages[storageID] = storage
-	d.storageSelector.AddStorage(storageID)
+	d.ringChangeFns = append(d.ringChangeFns, fn)
+}
+
+func (d *ObjectDistributor) AddStorage(storageID string, storage ObjectStorage, weight float64) {
+	d.AddZonedStorage(storageID, storage, weight, "")
+}
+
+// AddZonedStorage adds the storage in the zone, which is ignored by selectors that aren't zone aware.
+func (d *ObjectDistributor) AddZonedStorage(storageID string, storage ObjectStorage, weight float64, zone string) {
+	d.changeRing(func() {
+		d.storages[storageID] = storage
+		if zoned, ok := d.storageSelector.(ZonedStorageSelector); ok {
+			zoned.AddZonedStorage(storageID, weight, zone)
+			return
+		}
+		d.storageSelector.AddStorage(storageID, weight)
+	})
 }
 
 func (d *ObjectDistributor) RemoveStorage(storageID string) {
+	d.changeRing(func() {
+		delete(d.storages, storageID)
+		d.storageSelector.RemoveStorage(storageID)
+	})
+}
+
+// ReconfigureSelector runs change, which changes placement parameters of the storage selector, as a
+// membership change. Objects are then served from their previous owners until rebalanced.
+func (d *ObjectDistributor) ReconfigureSelector(change func()) {
+	d.changeRing(change)
+}
+
+func (d *ObjectDistributor) changeRing(change func()) {
 	d.l.Lock()
-	defer d.l.Unlock()
+	previous := d.storageSelector.Snapshot()
+	change()
+	current := d.storageSelector.Snapshot()
+	fns := d.ringChangeFns
+	d.l.Unlock()
+
+	for _, fn := range fns {
+		fn(previous, current)
+	}
+}
+
+// PutObject stores the object and returns the info of the copy it wrote.
+func (d *ObjectDistributor) PutObject(ctx context.Context, objectID string, r io.Reader, size int64, opts PutOptions) (_ core.ObjectInfo, err error) {
+	ctx, done := instrument(ctx, "put", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	replicas, err := d.getReplicas(objectID)
+	if err != nil {
+		return core.ObjectInfo{}, err
+	}
+	traceReplicas(ctx, replicas)
+
+	if opts.conditional() {
+		unlock := d.objectLocks.lock(objectID)
+		defer unlock()
+
+		if err := d.checkPreconditions(ctx, objectID, opts); err != nil {
+			return core.ObjectInfo{}, err
+		}
+	}
+
+	if len(replicas) == 1 && d.writeQuorum == 1 {
+		return replicas[0].storage.Put(ctx, objectID, r, size, opts.Metadata)
+	}
+	return d.putReplicated(ctx, objectID, replicas, r, size, opts.Metadata)
+}
+
+func (d *ObjectDistributor) GetObject(ctx context.Context, objectID string, opts GetOptions) (_ io.ReadCloser, _ core.ObjectInfo, err error) {
+	ctx, done := instrument(ctx, "get", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	replicas, err := d.getReplicas(objectID)
+	if err != nil {
+		return nil, core.ObjectInfo{}, err
+	}
+	traceReplicas(ctx, replicas)
+
+	if opts.Consistency == ConsistencyOne {
+		body, info, err := d.getFirst(ctx, objectID, replicas, opts.storageOptions())
+		if err == core.ErrNotFound {
+			return d.getFromPreviousOwners(ctx, objectID, replicas, opts.storageOptions())
+		}
+		return body, info, err
+	}
+
+	source, _, stale, err := d.locateNewest(ctx, objectID, replicas, opts.Consistency)
+	if err == core.ErrNotFound {
+		return d.getFromPreviousOwners(ctx, objectID, replicas, opts.storageOptions())
+	}
+	if err != nil {
+		return nil, core.ObjectInfo{}, err
+	}
 
-	delete(d.storages, storageID)
-	d.storageSelector.RemoveStorage(storageID)
+	traceSource(ctx, source)
+	body, info, err := source.storage.Get(ctx, objectID, opts.storageOptions())
+	if err != nil {
+		return nil, core.ObjectInfo{}, err
+	}
+
+	d.repair(objectID, source, info, stale)
+	return body, info, nil
 }
 
-func (d *ObjectDistributor) PutObject(ctx context.Context, objectID string, blob []byte) error {
-	objStorage, err := d.getObjectStorage(objectID)
+func (d *ObjectDistributor) StatObject(ctx context.Context, objectID string, opts GetOptions) (_ core.ObjectInfo, err error) {
+	ctx, done := instrument(ctx, "stat", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	replicas, err := d.getReplicas(objectID)
+	if err != nil {
+		return core.ObjectInfo{}, err
+	}
+	traceReplicas(ctx, replicas)
+
+	source, info, stale, err := d.locateNewest(ctx, objectID, replicas, opts.Consistency)
+	if err == core.ErrNotFound {
+		return d.statFromPreviousOwners(ctx, objectID, replicas)
+	}
+	if err != nil {
+		return core.ObjectInfo{}, err
+	}
+
+	traceSource(ctx, source)
+	d.repair(objectID, source, info, stale)
+	return info, nil
+}
+
+func (d *ObjectDistributor) DeleteObject(ctx context.Context, objectID string) (err error) {
+	ctx, done := instrument(ctx, "delete", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	replicas, err := d.getReplicas(objectID)
 	if err != nil {
 		return err
 	}
+	traceReplicas(ctx, replicas)
+
+	// The object is removed from every replica, it's only missing if no replica had it.
+	// Previous owners are included, otherwise the object would be served from them again.
+	resultErr := core.ErrNotFound
+	for _, replica := range append(replicas, d.getPreviousReplicas(objectID, replicas)...) {
+		err := replica.storage.Delete(ctx, objectID)
+		switch {
+		case err == nil:
+			if resultErr == core.ErrNotFound {
+				resultErr = nil
+			}
+		case err != core.ErrNotFound:
+			resultErr = fmt.Errorf("deleting object from '%s' storage: %w", replica.id, err)
+		}
+	}
+	return resultErr
+}
+
+// instrument starts measuring and tracing an operation, done records it once it returns
+// and takes a pointer to its named error result.
+func instrument(ctx context.Context, operation string, attrs ...attribute.KeyValue) (_ context.Context, done func(err *error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "distributor."+operation, attrs...)
+	return ctx, func(err *error) {
+		metrics.ObserveDistributorOperation(operation, start, *err)
+		tracing.End(span, *err)
+	}
+}
+
+// traceReplicas adds the storages selected for an object to the current span.
+func traceReplicas(ctx context.Context, replicas []replica) {
+	ids := make([]string, 0, len(replicas))
+	for _, rep := range replicas {
+		ids = append(ids, rep.id)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(tracing.StorageIDs(ids))
+}
+
+// traceSource adds the storage an object was read from to the current span.
+func traceSource(ctx context.Context, source replica) {
+	trace.SpanFromContext(ctx).SetAttributes(tracing.StorageID(source.id))
+}
 
-	return objStorage.Put(ctx, objectID, blob)
+type replica struct {
+	id      string
+	storage ObjectStorage
 }
 
-func (d *ObjectDistributor) GetObject(ctx context.Context, objectID string) ([]byte, error) {
-	objStorage, err := d.getObjectStorage(objectID)
+func (d *ObjectDistributor) snapshot() Ring {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	return d.storageSelector.Snapshot()
+}
+
+// Ring returns the current placement along with the number of storages every object is kept on.
+func (d *ObjectDistributor) Ring() (Ring, int) {
+	return d.snapshot(), d.replicas
+}
+
+// PrimaryStorage returns the current primary owner of the object, for state which has to stay on a single storage.
+func (d *ObjectDistributor) PrimaryStorage(objectID string) (string, ObjectStorage, error) {
+	replicas, err := d.getReplicas(objectID)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
+	return replicas[0].id, replicas[0].storage, nil
+}
 
-	return objStorage.Get(ctx, objectID)
+// Storage returns the registered storage with the ID.
+func (d *ObjectDistributor) Storage(storageID string) (ObjectStorage, bool) {
+	rep, ok := d.getStorage(storageID)
+	return rep.storage, ok
 }
 
-func (d *ObjectDistributor) getObjectStorage(objectID string) (ObjectStorage, error) {
+// getStorage returns the storage registered under storageID.
+func (d *ObjectDistributor) getStorage(storageID string) (replica, bool) {
 	d.l.RLock()
 	defer d.l.RUnlock()
 
-	storageID := d.storageSelector.LocateStorage(objectID)
 	objStorage, ok := d.storages[storageID]
-	if !ok {
-		return nil, fmt.Errorf("selected '%s' storage does not exist", storageID)
+	return replica{id: storageID, storage: objStorage}, ok
+}
+
+// listStorages returns all registered storages ordered by their ID.
+func (d *ObjectDistributor) listStorages() []replica {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	storages := make([]replica, 0, len(d.storages))
+	for storageID, objStorage := range d.storages {
+		storages = append(storages, replica{id: storageID, storage: objStorage})
+	}
+	sort.Slice(storages, func(i, j int) bool {
+		return storages[i].id < storages[j].id
+	})
+	return storages
+}
+
+func (d *ObjectDistributor) getReplicas(objectID string) ([]replica, error) {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	// Without any storage nothing could have been stored yet.
+	if len(d.storages) == 0 {
+		return nil, core.ErrNotFound
+	}
+
+	storageIDs := d.storageSelector.LocateStorages(objectID, d.replicas)
+	replicas := make([]replica, 0, len(storageIDs))
+	for _, storageID := range storageIDs {
+		objStorage, ok := d.storages[storageID]
+		if !ok {
+			return nil, fmt.Errorf("selected '%s' storage does not exist", storageID)
+		}
+		replicas = append(replicas, replica{id: storageID, storage: objStorage})
 	}
+	if len(replicas) == 0 {
+		return nil, fmt.Errorf("no storage selected for '%s' object", objectID)
+	}
+
+	return replicas, nil
+}
 
-	return objStorage, nil
+func logReplicaError(objectID, storageID string, err error, msg string) {
+	logrus.WithFields(logrus.Fields{
+		"id":        objectID,
+		"storageID": storageID,
+	}).WithError(err).Warn(msg)
 }
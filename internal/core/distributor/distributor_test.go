@@ -1,12 +1,15 @@
 package distributor
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"testing"
 
 	"github.com/spacelift-io/homework-object-storage/internal/core"
 	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func constSelector(storageID int) func(ctx context.Context, objectID string, storageIDs []int) (int, error) {
@@ -15,29 +18,53 @@ func constSelector(storageID int) func(ctx context.Context, objectID string, sto
 	}
 }
 
+func putObject(distributor *ObjectDistributor, objectID string, blob []byte) error {
+	_, err := distributor.PutObject(context.TODO(), objectID, bytes.NewReader(blob), int64(len(blob)), PutOptions{})
+	return err
+}
+
+func getObject(distributor *ObjectDistributor, objectID string) ([]byte, error) {
+	body, _, err := distributor.GetObject(context.TODO(), objectID, GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
 func TestObjectDistributor(t *testing.T) {
 	t.Run("when given object, it is put in object storage", func(t *testing.T) {
 		const objectID = "object_id"
 
 		distributor := NewObjectDistributor(newMemoryStorageSelector())
-		distributor.AddStorage("storage_id", memory.NewObjectStorage())
+		distributor.AddStorage("storage_id", memory.NewObjectStorage(), 1)
 
 		blob := []byte("Hello")
 
-		err := distributor.PutObject(context.TODO(), objectID, blob)
+		stored, err := distributor.PutObject(context.TODO(), objectID, bytes.NewReader(blob), int64(len(blob)), PutOptions{})
 		assert.NoError(t, err)
 
-		actualObject, err := distributor.GetObject(context.TODO(), objectID)
+		actualObject, err := getObject(distributor, objectID)
 		assert.NoError(t, err)
 		assert.Equal(t, blob, actualObject)
 
+		info, err := distributor.StatObject(context.TODO(), objectID, GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, info, stored)
+
+		t.Run("when read is pinned to another version, should return precondition failed", func(t *testing.T) {
+			_, _, err := distributor.GetObject(context.TODO(), objectID, GetOptions{MatchETag: "other"})
+			assert.Equal(t, core.ErrPreconditionFailed, err)
+		})
+
 		t.Run("when different object is given, object will be overwritten", func(t *testing.T) {
 			blob := []byte("Hello second")
 
-			err := distributor.PutObject(context.TODO(), objectID, blob)
+			err := putObject(distributor, objectID, blob)
 			assert.NoError(t, err)
 
-			actualObject, err := distributor.GetObject(context.TODO(), objectID)
+			actualObject, err := getObject(distributor, objectID)
 			assert.NoError(t, err)
 			assert.Equal(t, blob, actualObject)
 		})
@@ -57,14 +84,14 @@ func TestObjectDistributor(t *testing.T) {
 			"3": memory.NewObjectStorage(),
 		}
 		for ID, storage := range memoryStorages {
-			distributor.AddStorage(ID, storage)
+			distributor.AddStorage(ID, storage, 1)
 		}
 
 		for objID, obj := range objects {
-			err := distributor.PutObject(context.TODO(), objID, obj)
+			err := putObject(distributor, objID, obj)
 			assert.NoError(t, err)
 
-			actualObject, err := distributor.GetObject(context.TODO(), objID)
+			actualObject, err := getObject(distributor, objID)
 			assert.NoError(t, err)
 			assert.Equal(t, obj, actualObject)
 		}
@@ -75,9 +102,79 @@ func TestObjectDistributor(t *testing.T) {
 		}
 	})
 
+	t.Run("when object is stated, its info is returned without body", func(t *testing.T) {
+		const objectID = "object_id"
+
+		distributor := NewObjectDistributor(newMemoryStorageSelector())
+		distributor.AddStorage("storage_id", memory.NewObjectStorage(), 1)
+
+		err := putObject(distributor, objectID, []byte("Hello"))
+		require.NoError(t, err)
+
+		info, err := distributor.StatObject(context.TODO(), objectID, GetOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, objectID, info.ID)
+		assert.Equal(t, int64(5), info.Size)
+		assert.NotEmpty(t, info.ETag)
+		assert.False(t, info.LastModified.IsZero())
+
+		_, err = distributor.StatObject(context.TODO(), "random_object_id", GetOptions{})
+		assert.Equal(t, core.ErrNotFound, err)
+	})
+
+	t.Run("when object is deleted, it can no longer be found", func(t *testing.T) {
+		const objectID = "object_id"
+
+		distributor := NewObjectDistributor(newMemoryStorageSelector())
+		distributor.AddStorage("storage_id", memory.NewObjectStorage(), 1)
+
+		err := putObject(distributor, objectID, []byte("Hello"))
+		require.NoError(t, err)
+
+		err = distributor.DeleteObject(context.TODO(), objectID)
+		assert.NoError(t, err)
+
+		_, err = getObject(distributor, objectID)
+		assert.Equal(t, core.ErrNotFound, err)
+
+		t.Run("when deleted again, we should return not found", func(t *testing.T) {
+			err := distributor.DeleteObject(context.TODO(), objectID)
+			assert.Equal(t, core.ErrNotFound, err)
+		})
+	})
+
 	t.Run("when unknown objectID is given, we should return not found", func(t *testing.T) {
 		distributor := NewObjectDistributor(newMemoryStorageSelector())
-		_, err := distributor.GetObject(context.TODO(), "random_object_id")
+		_, err := getObject(distributor, "random_object_id")
 		assert.Equal(t, core.ErrNotFound, err)
 	})
+
+	t.Run("when every storage is removed, no storage is located", func(t *testing.T) {
+		selector := newMemoryStorageSelector()
+		distributor := NewObjectDistributor(selector)
+		distributor.AddStorage("storage_id", memory.NewObjectStorage(), 1)
+		distributor.RemoveStorage("storage_id")
+
+		assert.Equal(t, "", selector.LocateStorage("object_id"))
+		assert.Empty(t, selector.LocateStorages("object_id", 1))
+		_, err := getObject(distributor, "object_id")
+		assert.Equal(t, core.ErrNotFound, err)
+	})
+
+	t.Run("when selector is reconfigured, ring change is reported", func(t *testing.T) {
+		selector := newMemoryStorageSelector()
+		distributor := NewObjectDistributor(selector)
+		distributor.AddStorage("storage_id", memory.NewObjectStorage(), 1)
+
+		var previous, current Ring
+		distributor.OnRingChange(func(p, c Ring) {
+			previous, current = p, c
+		})
+		distributor.ReconfigureSelector(func() {
+			selector.AddStorage("other_storage_id", 1)
+		})
+
+		require.NotNil(t, current)
+		assert.Equal(t, previous.Version()+1, current.Version())
+	})
 }
@@ -0,0 +1,80 @@
+package distributor
+
+import (
+	"context"
+	"io"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+)
+
+// getPreviousReplicas returns owners of the object under the previous ring which don't own it anymore.
+// Until the rebalancer moves the object, it is only present on them.
+func (d *ObjectDistributor) getPreviousReplicas(objectID string, current []replica) []replica {
+	d.l.RLock()
+	defer d.l.RUnlock()
+
+	previous := d.storageSelector.Previous()
+	if previous == nil {
+		return nil
+	}
+
+	replicas := make([]replica, 0)
+	for _, storageID := range previous.LocateStorages(objectID, d.replicas) {
+		if containsReplica(current, storageID) {
+			continue
+		}
+		// Storages which left the ring can't be asked anymore.
+		if objStorage, ok := d.storages[storageID]; ok {
+			replicas = append(replicas, replica{id: storageID, storage: objStorage})
+		}
+	}
+	return replicas
+}
+
+func (d *ObjectDistributor) getFromPreviousOwners(ctx context.Context, objectID string, current []replica, opts core.GetOptions) (io.ReadCloser, core.ObjectInfo, error) {
+	for _, rep := range d.getPreviousReplicas(objectID, current) {
+		body, info, err := rep.storage.Get(ctx, objectID, opts)
+		switch err {
+		case nil:
+			traceSource(ctx, rep)
+			d.migrate(objectID, rep, info, current)
+			return body, info, nil
+		case core.ErrNotFound:
+		default:
+			logReplicaError(objectID, rep.id, err, "getting object from previous owner")
+		}
+	}
+	return nil, core.ObjectInfo{}, core.ErrNotFound
+}
+
+func (d *ObjectDistributor) statFromPreviousOwners(ctx context.Context, objectID string, current []replica) (core.ObjectInfo, error) {
+	for _, rep := range d.getPreviousReplicas(objectID, current) {
+		info, err := rep.storage.Stat(ctx, objectID)
+		switch err {
+		case nil:
+			traceSource(ctx, rep)
+			d.migrate(objectID, rep, info, current)
+			return info, nil
+		case core.ErrNotFound:
+		default:
+			logReplicaError(objectID, rep.id, err, "getting object info from previous owner")
+		}
+	}
+	return core.ObjectInfo{}, core.ErrNotFound
+}
+
+// migrate moves the object found on a previous owner to its current owners ahead of the rebalancer.
+func (d *ObjectDistributor) migrate(objectID string, source replica, info core.ObjectInfo, current []replica) {
+	if d.migrateFallback {
+		d.repair(objectID, source, info, current)
+	}
+}
+
+func containsReplica(replicas []replica, storageID string) bool {
+	for _, rep := range replicas {
+		if rep.id == storageID {
+			return true
+		}
+	}
+	return false
+}
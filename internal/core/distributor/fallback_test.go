@@ -0,0 +1,76 @@
+package distributor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectDistributorFallback(t *testing.T) {
+	putObjects := func(t *testing.T, distributor *ObjectDistributor) map[string][]byte {
+		objects := make(map[string][]byte)
+		for i := 0; i < 10; i++ {
+			objectID := fmt.Sprintf("object_%d", i)
+			objects[objectID] = []byte(objectID)
+			require.NoError(t, putObject(distributor, objectID, objects[objectID]))
+		}
+		return objects
+	}
+
+	t.Run("when storage joins, objects are served from their previous owner and migrated", func(t *testing.T) {
+		distributor := NewObjectDistributor(newMemoryStorageSelector(), WithFallbackMigration())
+		distributor.AddStorage("1", memory.NewObjectStorage(), 1)
+		objects := putObjects(t, distributor)
+
+		newStorage := memory.NewObjectStorage()
+		distributor.AddStorage("2", newStorage, 1)
+
+		for objectID, blob := range objects {
+			actualObject, err := getObject(distributor, objectID)
+			assert.NoError(t, err)
+			assert.Equal(t, blob, actualObject)
+		}
+
+		distributor.repairs.Wait()
+		assert.NotZero(t, newStorage.ObjectCount())
+	})
+
+	t.Run("when migration is disabled, objects are only served from their previous owner", func(t *testing.T) {
+		distributor := NewObjectDistributor(newMemoryStorageSelector())
+		distributor.AddStorage("1", memory.NewObjectStorage(), 1)
+		objects := putObjects(t, distributor)
+
+		newStorage := memory.NewObjectStorage()
+		distributor.AddStorage("2", newStorage, 1)
+
+		for objectID := range objects {
+			info, err := distributor.StatObject(context.TODO(), objectID, GetOptions{})
+			assert.NoError(t, err)
+			assert.Equal(t, objectID, info.ID)
+		}
+
+		distributor.repairs.Wait()
+		assert.Zero(t, newStorage.ObjectCount())
+	})
+
+	t.Run("when object is deleted, it is removed from its previous owner too", func(t *testing.T) {
+		distributor := NewObjectDistributor(newMemoryStorageSelector())
+		oldStorage := memory.NewObjectStorage()
+		distributor.AddStorage("1", oldStorage, 1)
+		objects := putObjects(t, distributor)
+		distributor.AddStorage("2", memory.NewObjectStorage(), 1)
+
+		for objectID := range objects {
+			require.NoError(t, distributor.DeleteObject(context.TODO(), objectID))
+
+			_, err := getObject(distributor, objectID)
+			assert.Equal(t, core.ErrNotFound, err)
+		}
+		assert.Zero(t, oldStorage.ObjectCount())
+	})
+}
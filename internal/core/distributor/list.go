@@ -0,0 +1,103 @@
+package distributor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+)
+
+// ListObjects lists objects of all storages in ID order. Replicas of an object are reported once,
+// by their newest copy. The returned bool is true when more objects follow the listed ones.
+func (d *ObjectDistributor) ListObjects(ctx context.Context, opts core.ListOptions) (_ []core.ObjectInfo, _ bool, err error) {
+	ctx, done := instrument(ctx, "list")
+	defer done(&err)
+
+	storages := d.listStorages()
+
+	// One extra object per storage tells whether the listing is truncated.
+	storageOpts := opts
+	if opts.Limit > 0 {
+		storageOpts.Limit = opts.Limit + 1
+	}
+
+	lists := make([][]core.ObjectInfo, len(storages))
+	errs := make([]error, len(storages))
+	var wg sync.WaitGroup
+	for i, storage := range storages {
+		wg.Add(1)
+		go func(i int, storage replica) {
+			defer wg.Done()
+			lists[i], errs[i] = listStorage(ctx, storage.storage, storageOpts)
+		}(i, storage)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, false, fmt.Errorf("listing objects of '%s' storage: %w", storages[i].id, err)
+		}
+	}
+
+	objects := mergeObjectLists(lists)
+	if opts.Limit > 0 && len(objects) > opts.Limit {
+		return objects[:opts.Limit], true, nil
+	}
+	return objects, false, nil
+}
+
+// listStorage lists objects of a single storage leaving out internal objects. Pages are
+// fetched until the limit is reached, so internal objects don't make listings look complete.
+func listStorage(ctx context.Context, storage ObjectStorage, opts core.ListOptions) ([]core.ObjectInfo, error) {
+	limit := opts.Limit
+	objects := make([]core.ObjectInfo, 0)
+	for {
+		page, err := storage.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, info := range page {
+			if !core.IsInternalObject(info.ID) {
+				objects = append(objects, info)
+			}
+		}
+		if limit == 0 || len(page) < opts.Limit || len(objects) >= limit {
+			return objects, nil
+		}
+		opts.StartAfter = page[len(page)-1].ID
+		opts.Limit = limit - len(objects)
+	}
+}
+
+// mergeObjectLists merges lists sorted by ID into one, keeping the newest copy of duplicate IDs.
+func mergeObjectLists(lists [][]core.ObjectInfo) []core.ObjectInfo {
+	merged := make([]core.ObjectInfo, 0)
+	positions := make([]int, len(lists))
+	for {
+		next := -1
+		for i, list := range lists {
+			if positions[i] >= len(list) {
+				continue
+			}
+			if next == -1 || list[positions[i]].ID < lists[next][positions[next]].ID {
+				next = i
+			}
+		}
+		if next == -1 {
+			return merged
+		}
+
+		info := lists[next][positions[next]]
+		positions[next]++
+
+		if last := len(merged) - 1; last >= 0 && merged[last].ID == info.ID {
+			if info.LastModified.After(merged[last].LastModified) {
+				merged[last] = info
+			}
+			continue
+		}
+		merged = append(merged, info)
+	}
+}
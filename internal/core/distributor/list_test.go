@@ -0,0 +1,82 @@
+package distributor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectDistributorListObjects(t *testing.T) {
+	distributor := NewObjectDistributor(newMemoryStorageSelector(), WithReplication(2, 2))
+	for i := 0; i < 3; i++ {
+		distributor.AddStorage(fmt.Sprint(i), memory.NewObjectStorage(), 1)
+	}
+
+	expectedIDs := make([]string, 0)
+	for i := 0; i < 10; i++ {
+		objectID := fmt.Sprintf("object_%d", i)
+		expectedIDs = append(expectedIDs, objectID)
+		require.NoError(t, putObject(distributor, objectID, []byte(objectID)))
+	}
+	require.NoError(t, putObject(distributor, "other", []byte("other")))
+
+	t.Run("objects are listed once in order across storages", func(t *testing.T) {
+		objects, truncated, err := distributor.ListObjects(context.TODO(), core.ListOptions{Prefix: "object_"})
+		require.NoError(t, err)
+		assert.False(t, truncated)
+
+		actualIDs := make([]string, 0)
+		for _, info := range objects {
+			actualIDs = append(actualIDs, info.ID)
+		}
+		assert.Equal(t, expectedIDs, actualIDs)
+	})
+
+	t.Run("objects are listed page by page", func(t *testing.T) {
+		actualIDs := make([]string, 0)
+		startAfter := ""
+		for {
+			objects, truncated, err := distributor.ListObjects(context.TODO(), core.ListOptions{Prefix: "object_", StartAfter: startAfter, Limit: 3})
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(objects), 3)
+
+			for _, info := range objects {
+				actualIDs = append(actualIDs, info.ID)
+			}
+			if !truncated {
+				break
+			}
+			startAfter = objects[len(objects)-1].ID
+		}
+		assert.Equal(t, expectedIDs, actualIDs)
+	})
+
+	t.Run("internal objects are not listed", func(t *testing.T) {
+		storage, _ := distributor.Storage("0")
+		for i := 0; i < 5; i++ {
+			objectID := fmt.Sprintf("%supload_%d", core.InternalObjectPrefix, i)
+			_, err := storage.Put(context.TODO(), objectID, bytes.NewReader(nil), 0, core.ObjectMetadata{})
+			require.NoError(t, err)
+		}
+
+		objects, truncated, err := distributor.ListObjects(context.TODO(), core.ListOptions{Limit: 3})
+		require.NoError(t, err)
+		assert.True(t, truncated)
+		require.Len(t, objects, 3)
+		assert.Equal(t, "object_0", objects[0].ID)
+	})
+
+	t.Run("when storage fails, listing fails", func(t *testing.T) {
+		distributor.AddStorage("failing", failingStorage{}, 1)
+		defer distributor.RemoveStorage("failing")
+
+		_, _, err := distributor.ListObjects(context.TODO(), core.ListOptions{})
+		assert.ErrorIs(t, err, errStorageFailed)
+	})
+}
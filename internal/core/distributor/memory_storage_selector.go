@@ -5,6 +5,8 @@ import "hash/fnv"
 type memoryStorageSelector struct {
 	storages []string
 	it       int
+	version  uint64
+	previous Ring
 }
 
 func newMemoryStorageSelector() *memoryStorageSelector {
@@ -14,11 +16,15 @@ func newMemoryStorageSelector() *memoryStorageSelector {
 	}
 }
 
-func (m *memoryStorageSelector) AddStorage(storageID string) {
+func (m *memoryStorageSelector) AddStorage(storageID string, weight float64) {
+	m.previous = m.Snapshot()
+	m.version++
 	m.storages = append(m.storages, storageID)
 }
 
 func (m *memoryStorageSelector) RemoveStorage(storageID string) {
+	m.previous = m.Snapshot()
+	m.version++
 	for i, ID := range m.storages {
 		if ID == storageID {
 			m.storages = append(m.storages[:i], m.storages[i+1:]...)
@@ -28,10 +34,44 @@ func (m *memoryStorageSelector) RemoveStorage(storageID string) {
 }
 
 func (m *memoryStorageSelector) LocateStorage(objectID string) string {
+	if len(m.storages) == 0 {
+		return ""
+	}
 	hashedID := objectIDHashed(objectID)
 	return m.storages[hashedID%uint64(len(m.storages))]
 }
 
+func (m *memoryStorageSelector) LocateStorages(objectID string, count int) []string {
+	if count > len(m.storages) {
+		count = len(m.storages)
+	}
+	if count < 1 {
+		return nil
+	}
+
+	first := objectIDHashed(objectID) % uint64(len(m.storages))
+	storageIDs := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		storageIDs = append(storageIDs, m.storages[(first+uint64(i))%uint64(len(m.storages))])
+	}
+	return storageIDs
+}
+
+func (m *memoryStorageSelector) Snapshot() Ring {
+	return &memoryStorageSelector{
+		storages: append([]string(nil), m.storages...),
+		version:  m.version,
+	}
+}
+
+func (m *memoryStorageSelector) Previous() Ring {
+	return m.previous
+}
+
+func (m *memoryStorageSelector) Version() uint64 {
+	return m.version
+}
+
 func objectIDHashed(objectID string) uint64 {
 	hash := fnv.New64a()
 	_, err := hash.Write([]byte(objectID))
@@ -0,0 +1,186 @@
+package distributor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+)
+
+const (
+	// uploadIDSeparator joins the storage ID, the staging token, the object digest and the storage's own
+	// upload ID in the upload IDs handed out. Only the last one may contain it, the others are container IDs or hex.
+	uploadIDSeparator = "/"
+	stagingPrefix     = core.InternalObjectPrefix + "multipart/"
+)
+
+var ErrMultipartNotSupported = errors.New("storage does not support multipart uploads")
+
+// MultipartStorage is implemented by storages able to assemble an object from separately uploaded parts.
+type MultipartStorage interface {
+	InitiateMultipart(ctx context.Context, objectID string, metadata core.ObjectMetadata) (string, error)
+	// PutPart stores the part, replacing a previously uploaded part with the same number.
+	PutPart(ctx context.Context, objectID, uploadID string, partNumber int, r io.Reader, size int64) (core.PartInfo, error)
+	// ListParts returns the uploaded parts ordered by their number.
+	ListParts(ctx context.Context, objectID, uploadID string) ([]core.PartInfo, error)
+	// CompleteMultipart assembles the object from the parts, which have to be ordered by their number.
+	CompleteMultipart(ctx context.Context, objectID, uploadID string, parts []core.PartInfo) (core.ObjectInfo, error)
+	AbortMultipart(ctx context.Context, objectID, uploadID string) error
+}
+
+// InitiateUpload starts a multipart upload of a staging object on the primary owner of the object.
+// The returned upload ID pins the upload to that storage, so parts keep going there even if the ring
+// changes meanwhile, and to the object, so it can't be used under any other object ID.
+func (d *ObjectDistributor) InitiateUpload(ctx context.Context, objectID string, metadata core.ObjectMetadata) (string, error) {
+	replicas, err := d.getReplicas(objectID)
+	if err != nil {
+		return "", err
+	}
+
+	primary := replicas[0]
+	multipartStorage, ok := primary.storage.(MultipartStorage)
+	if !ok {
+		return "", ErrMultipartNotSupported
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	up := upload{storageID: primary.id, token: hex.EncodeToString(token), objectDigest: objectDigest(objectID)}
+
+	if up.storageUploadID, err = multipartStorage.InitiateMultipart(ctx, up.stagingKey(), metadata); err != nil {
+		return "", err
+	}
+	return up.encode(), nil
+}
+
+func (d *ObjectDistributor) PutUploadPart(ctx context.Context, objectID, uploadID string, partNumber int, r io.Reader, size int64) (core.PartInfo, error) {
+	up, _, multipartStorage, err := d.getUpload(objectID, uploadID)
+	if err != nil {
+		return core.PartInfo{}, err
+	}
+	return multipartStorage.PutPart(ctx, up.stagingKey(), up.storageUploadID, partNumber, r, size)
+}
+
+func (d *ObjectDistributor) ListUploadParts(ctx context.Context, objectID, uploadID string) ([]core.PartInfo, error) {
+	up, _, multipartStorage, err := d.getUpload(objectID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return multipartStorage.ListParts(ctx, up.stagingKey(), up.storageUploadID)
+}
+
+// CompleteUpload assembles the staging object from the given parts, or from all uploaded parts when none
+// are given, and puts it to the object's replicas like any other object. Every replica then holds the
+// same plain put, so their ETags agree and repairs reproduce them. The staging object is removed either way.
+func (d *ObjectDistributor) CompleteUpload(ctx context.Context, objectID, uploadID string, parts []core.PartInfo) (core.ObjectInfo, error) {
+	up, source, multipartStorage, err := d.getUpload(objectID, uploadID)
+	if err != nil {
+		return core.ObjectInfo{}, err
+	}
+
+	if len(parts) == 0 {
+		if parts, err = multipartStorage.ListParts(ctx, up.stagingKey(), up.storageUploadID); err != nil {
+			return core.ObjectInfo{}, err
+		}
+		if len(parts) == 0 {
+			return core.ObjectInfo{}, core.ErrInvalidPart
+		}
+	}
+
+	if _, err := multipartStorage.CompleteMultipart(ctx, up.stagingKey(), up.storageUploadID, parts); err != nil {
+		return core.ObjectInfo{}, err
+	}
+	defer func() {
+		if err := source.storage.Delete(context.Background(), up.stagingKey()); err != nil && err != core.ErrNotFound {
+			logrus.WithFields(logrus.Fields{
+				"id":      objectID,
+				"storage": source.id,
+			}).WithError(err).Error("deleting staged upload")
+		}
+	}()
+
+	body, staged, err := source.storage.Get(ctx, up.stagingKey(), core.GetOptions{})
+	if err != nil {
+		return core.ObjectInfo{}, fmt.Errorf("reading staged upload: %w", err)
+	}
+	defer body.Close()
+
+	return d.PutObject(ctx, objectID, body, staged.Size, PutOptions{Metadata: staged.Metadata})
+}
+
+func (d *ObjectDistributor) AbortUpload(ctx context.Context, objectID, uploadID string) error {
+	up, _, multipartStorage, err := d.getUpload(objectID, uploadID)
+	if err != nil {
+		return err
+	}
+	return multipartStorage.AbortMultipart(ctx, up.stagingKey(), up.storageUploadID)
+}
+
+// getUpload resolves the storage the upload was initiated on, uploads of unknown storages or of other
+// objects are not found.
+func (d *ObjectDistributor) getUpload(objectID, uploadID string) (upload, replica, MultipartStorage, error) {
+	up, ok := decodeUploadID(uploadID)
+	if !ok || up.objectDigest != objectDigest(objectID) {
+		return upload{}, replica{}, nil, core.ErrNotFound
+	}
+
+	rep, ok := d.getStorage(up.storageID)
+	if !ok {
+		return upload{}, replica{}, nil, core.ErrNotFound
+	}
+	multipartStorage, ok := rep.storage.(MultipartStorage)
+	if !ok {
+		return upload{}, replica{}, nil, ErrMultipartNotSupported
+	}
+	return up, rep, multipartStorage, nil
+}
+
+// upload identifies a multipart upload of a staging object on a single storage.
+type upload struct {
+	storageID       string
+	token           string
+	objectDigest    string
+	storageUploadID string
+}
+
+func (u upload) stagingKey() string {
+	return stagingPrefix + u.token
+}
+
+func (u upload) encode() string {
+	fields := []string{u.storageID, u.token, u.objectDigest, u.storageUploadID}
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(fields, uploadIDSeparator)))
+}
+
+func decodeUploadID(uploadID string) (upload, bool) {
+	decoded, err := base64.RawURLEncoding.DecodeString(uploadID)
+	if err != nil {
+		return upload{}, false
+	}
+	fields := strings.SplitN(string(decoded), uploadIDSeparator, 4)
+	if len(fields) != 4 {
+		return upload{}, false
+	}
+	for _, field := range fields {
+		if field == "" {
+			return upload{}, false
+		}
+	}
+	return upload{storageID: fields[0], token: fields[1], objectDigest: fields[2], storageUploadID: fields[3]}, true
+}
+
+// objectDigest binds an upload to its object without carrying the whole object ID in the upload ID.
+func objectDigest(objectID string) string {
+	sum := sha256.Sum256([]byte(objectID))
+	return hex.EncodeToString(sum[:16])
+}
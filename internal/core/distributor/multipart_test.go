@@ -0,0 +1,138 @@
+package distributor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func putPart(distributor *ObjectDistributor, objectID, uploadID string, partNumber int, blob []byte) (core.PartInfo, error) {
+	return distributor.PutUploadPart(context.TODO(), objectID, uploadID, partNumber, bytes.NewReader(blob), int64(len(blob)))
+}
+
+func TestObjectDistributorMultipartUpload(t *testing.T) {
+	const objectID = "object_id"
+
+	storages := make([]*memory.ObjectStorage, 0)
+	distributor := NewObjectDistributor(newMemoryStorageSelector(), WithReplication(2, 2))
+	for i := 0; i < 2; i++ {
+		storage := memory.NewObjectStorage()
+		storages = append(storages, storage)
+		distributor.AddStorage(fmt.Sprint(i), storage, 1)
+	}
+
+	t.Run("parts uploaded out of order are assembled and replicated", func(t *testing.T) {
+		uploadID, err := distributor.InitiateUpload(context.TODO(), objectID, core.ObjectMetadata{ContentType: "text/plain"})
+		require.NoError(t, err)
+
+		_, err = putPart(distributor, objectID, uploadID, 2, []byte("world"))
+		require.NoError(t, err)
+		_, err = putPart(distributor, objectID, uploadID, 1, []byte("hello "))
+		require.NoError(t, err)
+		// A retried part replaces the previous attempt.
+		_, err = putPart(distributor, objectID, uploadID, 1, []byte("hello, "))
+		require.NoError(t, err)
+
+		parts, err := distributor.ListUploadParts(context.TODO(), objectID, uploadID)
+		require.NoError(t, err)
+		assert.Len(t, parts, 2)
+
+		info, err := distributor.CompleteUpload(context.TODO(), objectID, uploadID, nil)
+		require.NoError(t, err)
+		assert.Equal(t, int64(12), info.Size)
+
+		for _, storage := range storages {
+			assert.Equal(t, []byte("hello, world"), readStorage(t, storage, objectID))
+
+			stored, err := storage.Stat(context.TODO(), objectID)
+			require.NoError(t, err)
+			assert.Equal(t, "text/plain", stored.Metadata.ContentType)
+			assert.Equal(t, info.ETag, stored.ETag)
+
+			staged, err := storage.List(context.TODO(), core.ListOptions{Prefix: core.InternalObjectPrefix})
+			require.NoError(t, err)
+			assert.Empty(t, staged)
+		}
+
+		_, err = putPart(distributor, objectID, uploadID, 3, []byte("!"))
+		assert.Equal(t, core.ErrNotFound, err)
+	})
+
+	t.Run("upload stays on its storage when the ring changes", func(t *testing.T) {
+		uploadID, err := distributor.InitiateUpload(context.TODO(), objectID, core.ObjectMetadata{})
+		require.NoError(t, err)
+
+		distributor.AddStorage("2", memory.NewObjectStorage(), 1)
+		defer distributor.RemoveStorage("2")
+
+		_, err = putPart(distributor, objectID, uploadID, 1, []byte("blob"))
+		require.NoError(t, err)
+		_, err = distributor.CompleteUpload(context.TODO(), objectID, uploadID, nil)
+		require.NoError(t, err)
+
+		blob, err := getObject(distributor, objectID)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("blob"), blob)
+	})
+
+	t.Run("when completing with unknown part, should return invalid part", func(t *testing.T) {
+		uploadID, err := distributor.InitiateUpload(context.TODO(), objectID, core.ObjectMetadata{})
+		require.NoError(t, err)
+
+		_, err = distributor.CompleteUpload(context.TODO(), objectID, uploadID, []core.PartInfo{{PartNumber: 1, ETag: "etag"}})
+		assert.Equal(t, core.ErrInvalidPart, err)
+	})
+
+	t.Run("when a replica fails, completing should not reach the write quorum", func(t *testing.T) {
+		uploadID, err := distributor.InitiateUpload(context.TODO(), objectID, core.ObjectMetadata{})
+		require.NoError(t, err)
+		_, err = putPart(distributor, objectID, uploadID, 1, []byte("blob"))
+		require.NoError(t, err)
+
+		distributor.RemoveStorage("1")
+		distributor.AddStorage("1", failingStorage{}, 1)
+		defer func() {
+			distributor.RemoveStorage("1")
+			distributor.AddStorage("1", storages[1], 1)
+		}()
+
+		_, err = distributor.CompleteUpload(context.TODO(), objectID, uploadID, nil)
+		assert.ErrorIs(t, err, core.ErrQuorumNotReached)
+	})
+
+	t.Run("when used under another object ID, upload is not found", func(t *testing.T) {
+		uploadID, err := distributor.InitiateUpload(context.TODO(), objectID, core.ObjectMetadata{})
+		require.NoError(t, err)
+		_, err = putPart(distributor, objectID, uploadID, 1, []byte("hello"))
+		require.NoError(t, err)
+
+		_, err = putPart(distributor, "other_id", uploadID, 2, []byte("world"))
+		assert.Equal(t, core.ErrNotFound, err)
+		_, err = distributor.CompleteUpload(context.TODO(), "other_id", uploadID, nil)
+		assert.Equal(t, core.ErrNotFound, err)
+		assert.Equal(t, core.ErrNotFound, distributor.AbortUpload(context.TODO(), "other_id", uploadID))
+		for _, storage := range storages {
+			_, err := storage.Stat(context.TODO(), "other_id")
+			assert.Equal(t, core.ErrNotFound, err)
+		}
+
+		require.NoError(t, distributor.AbortUpload(context.TODO(), objectID, uploadID))
+	})
+
+	t.Run("aborted upload is not found", func(t *testing.T) {
+		uploadID, err := distributor.InitiateUpload(context.TODO(), objectID, core.ObjectMetadata{})
+		require.NoError(t, err)
+
+		require.NoError(t, distributor.AbortUpload(context.TODO(), objectID, uploadID))
+		assert.Equal(t, core.ErrNotFound, distributor.AbortUpload(context.TODO(), objectID, uploadID))
+
+		_, err = distributor.ListUploadParts(context.TODO(), objectID, "malformed")
+		assert.Equal(t, core.ErrNotFound, err)
+	})
+}
@@ -0,0 +1,358 @@
+package distributor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+)
+
+const (
+	rebalanceListPageSize = 1000
+	// rebalanceSettleDelay lets a burst of membership changes, such as startup discovery, settle into one run.
+	rebalanceSettleDelay = 5 * time.Second
+)
+
+// Rebalancer moves objects to their new owners after storages join or leave the ring,
+// so objects stored under the previous placement don't turn into 404s.
+type Rebalancer struct {
+	distributor      *ObjectDistributor
+	objectsPerSecond int
+	settleDelay      time.Duration
+
+	changed chan struct{}
+
+	l        sync.Mutex
+	baseline Ring
+	progress RebalanceProgress
+}
+
+type RebalanceProgress struct {
+	Running         bool      `json:"running"`
+	StartedAt       time.Time `json:"startedAt,omitempty"`
+	FinishedAt      time.Time `json:"finishedAt,omitempty"`
+	MovedPartitions int       `json:"movedPartitions"`
+	ScannedObjects  int       `json:"scannedObjects"`
+	CopiedObjects   int       `json:"copiedObjects"`
+	// DeletedObjects counts copies removed from storages which don't own them anymore.
+	DeletedObjects int `json:"deletedObjects"`
+	FailedObjects  int `json:"failedObjects"`
+}
+
+// NewRebalancer creates a rebalancer for the distributor, copying at most objectsPerSecond objects.
+// A non-positive objectsPerSecond disables throttling.
+func NewRebalancer(distributor *ObjectDistributor, objectsPerSecond int) *Rebalancer {
+	r := &Rebalancer{
+		distributor:      distributor,
+		objectsPerSecond: objectsPerSecond,
+		settleDelay:      rebalanceSettleDelay,
+		changed:          make(chan struct{}, 1),
+	}
+	distributor.OnRingChange(r.ringChanged)
+	return r
+}
+
+// SetObjectsPerSecond changes the throttling, a running rebalance picks it up with the next copied object.
+func (r *Rebalancer) SetObjectsPerSecond(objectsPerSecond int) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	r.objectsPerSecond = objectsPerSecond
+}
+
+func (r *Rebalancer) Progress() RebalanceProgress {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	return r.progress
+}
+
+func (r *Rebalancer) ringChanged(previous, current Ring) {
+	r.l.Lock()
+	// Until a run completes, objects are still placed according to the oldest unprocessed ring.
+	if r.baseline == nil {
+		r.baseline = previous
+	}
+	r.l.Unlock()
+
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Run rebalances after every ring change until ctx is done. A change during a run restarts it.
+func (r *Rebalancer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.changed:
+		}
+
+		if !r.settle(ctx) {
+			return
+		}
+
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan bool, 1)
+		go func() {
+			done <- r.rebalance(runCtx)
+		}()
+
+		select {
+		case completed := <-done:
+			cancel()
+			if completed {
+				r.l.Lock()
+				r.baseline = nil
+				r.l.Unlock()
+			} else if ctx.Err() == nil {
+				// Keeps the baseline and retries once the settle delay passes.
+				select {
+				case r.changed <- struct{}{}:
+				default:
+				}
+			}
+		case <-r.changed:
+			cancel()
+			<-done
+			// Pushes the change back, so the next iteration starts over with the same baseline.
+			select {
+			case r.changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// settle waits until no ring change happened for the settle delay.
+func (r *Rebalancer) settle(ctx context.Context) bool {
+	t := time.NewTimer(r.settleDelay)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-r.changed:
+			if !t.Stop() {
+				<-t.C
+			}
+			t.Reset(r.settleDelay)
+		case <-t.C:
+			return true
+		}
+	}
+}
+
+// rebalance makes sure every object whose owners changed between the baseline and the current ring
+// is present on its current owners. Copies on storages which don't own the object anymore are removed
+// once every owner holds it, so they can't be served in place of newer writes under a later ring.
+func (r *Rebalancer) rebalance(ctx context.Context) bool {
+	r.l.Lock()
+	previous := r.baseline
+	r.l.Unlock()
+
+	current := r.distributor.snapshot()
+	if previous == nil || isEmpty(previous) {
+		// Objects can't have been placed by an empty ring, there is nothing to move.
+		return true
+	}
+
+	moved, partitioned := movedPartitions(previous, current, r.distributor.replicas)
+	if partitioned && len(moved) == 0 {
+		return true
+	}
+
+	r.updateProgress(func(p *RebalanceProgress) {
+		*p = RebalanceProgress{
+			Running:         true,
+			StartedAt:       time.Now(),
+			MovedPartitions: len(moved),
+		}
+	})
+	logrus.WithField("movedPartitions", len(moved)).Info("rebalancing storages")
+
+	var lastCopy time.Time
+	completed := true
+	for _, source := range r.distributor.listStorages() {
+		if !r.rebalanceStorage(ctx, source, previous, current, moved, &lastCopy) {
+			completed = false
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+
+	progress := r.updateProgress(func(p *RebalanceProgress) {
+		p.Running = false
+		p.FinishedAt = time.Now()
+	})
+	logrus.WithFields(logrus.Fields{
+		"completed":      completed,
+		"scannedObjects": progress.ScannedObjects,
+		"copiedObjects":  progress.CopiedObjects,
+		"deletedObjects": progress.DeletedObjects,
+		"failedObjects":  progress.FailedObjects,
+	}).Info("rebalancing finished")
+	return completed
+}
+
+func (r *Rebalancer) rebalanceStorage(ctx context.Context, source replica, previous, current Ring, moved map[int]bool, lastCopy *time.Time) bool {
+	partitioned, _ := current.(PartitionedRing)
+
+	startAfter := ""
+	for {
+		objects, err := source.storage.List(ctx, core.ListOptions{StartAfter: startAfter, Limit: rebalanceListPageSize})
+		if err != nil {
+			logReplicaError("", source.id, err, "listing objects for rebalancing")
+			return false
+		}
+
+		for _, info := range objects {
+			r.updateProgress(func(p *RebalanceProgress) { p.ScannedObjects++ })
+			// Internal objects belong to the storage they were written to, not to the ring.
+			if core.IsInternalObject(info.ID) {
+				continue
+			}
+			if partitioned != nil && moved != nil && !moved[partitioned.LocatePartition(info.ID)] {
+				continue
+			}
+
+			previousOwners := previous.LocateStorages(info.ID, r.distributor.replicas)
+			owners := current.LocateStorages(info.ID, r.distributor.replicas)
+			for _, ownerID := range owners {
+				if ownerID == source.id || contains(previousOwners, ownerID) {
+					continue
+				}
+				target, ok := r.distributor.getStorage(ownerID)
+				if !ok {
+					continue
+				}
+
+				if !r.throttle(ctx, lastCopy) {
+					return false
+				}
+
+				copied, err := r.distributor.copyReplica(ctx, info.ID, source, info, target)
+				if err != nil {
+					if ctx.Err() != nil {
+						return false
+					}
+					logReplicaError(info.ID, target.id, err, "copying object while rebalancing")
+					r.updateProgress(func(p *RebalanceProgress) { p.FailedObjects++ })
+					continue
+				}
+				if copied {
+					r.updateProgress(func(p *RebalanceProgress) { p.CopiedObjects++ })
+				}
+			}
+
+			if !contains(owners, source.id) && r.deleteMovedCopy(ctx, source, info, owners) {
+				r.updateProgress(func(p *RebalanceProgress) { p.DeletedObjects++ })
+			}
+		}
+
+		if ctx.Err() != nil {
+			return false
+		}
+		if len(objects) < rebalanceListPageSize {
+			return true
+		}
+		startAfter = objects[len(objects)-1].ID
+	}
+}
+
+// deleteMovedCopy removes the copy from source, which doesn't own the object anymore, once every owner
+// is confirmed to hold the same or a newer copy.
+func (r *Rebalancer) deleteMovedCopy(ctx context.Context, source replica, info core.ObjectInfo, owners []string) bool {
+	for _, ownerID := range owners {
+		target, ok := r.distributor.getStorage(ownerID)
+		if !ok {
+			return false
+		}
+		targetInfo, err := target.storage.Stat(ctx, info.ID)
+		if err != nil || (targetInfo.ETag != info.ETag && !targetInfo.LastModified.After(info.LastModified)) {
+			return false
+		}
+	}
+
+	if err := source.storage.Delete(ctx, info.ID); err != nil && err != core.ErrNotFound {
+		logReplicaError(info.ID, source.id, err, "deleting moved object")
+		return false
+	}
+	return true
+}
+
+// throttle waits until another object can be copied without exceeding the current rate, which may
+// change during a run. It returns false when ctx is done first.
+func (r *Rebalancer) throttle(ctx context.Context, lastCopy *time.Time) bool {
+	r.l.Lock()
+	objectsPerSecond := r.objectsPerSecond
+	r.l.Unlock()
+
+	if objectsPerSecond > 0 {
+		if wait := time.Until(lastCopy.Add(time.Second / time.Duration(objectsPerSecond))); wait > 0 {
+			t := time.NewTimer(wait)
+			defer t.Stop()
+
+			select {
+			case <-ctx.Done():
+				return false
+			case <-t.C:
+			}
+		}
+	}
+	*lastCopy = time.Now()
+	return true
+}
+
+func (r *Rebalancer) updateProgress(update func(p *RebalanceProgress)) RebalanceProgress {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	update(&r.progress)
+	return r.progress
+}
+
+// movedPartitions returns partitions whose owners differ between the rings. When rings
+// aren't partitioned, owners have to be compared object by object and false is returned.
+func movedPartitions(previous, current Ring, replicas int) (map[int]bool, bool) {
+	previousPartitioned, ok := previous.(PartitionedRing)
+	if !ok {
+		return nil, false
+	}
+	currentPartitioned, ok := current.(PartitionedRing)
+	if !ok || previousPartitioned.PartitionCount() != currentPartitioned.PartitionCount() {
+		return nil, false
+	}
+
+	moved := make(map[int]bool)
+	for partitionID := 0; partitionID < currentPartitioned.PartitionCount(); partitionID++ {
+		previousOwners := previousPartitioned.PartitionOwners(partitionID, replicas)
+		for _, ownerID := range currentPartitioned.PartitionOwners(partitionID, replicas) {
+			if !contains(previousOwners, ownerID) {
+				moved[partitionID] = true
+				break
+			}
+		}
+	}
+	return moved, true
+}
+
+// isEmpty reports whether the ring has no storages, an empty ring locates no storage for any object.
+func isEmpty(ring Ring) bool {
+	return len(ring.LocateStorages("", 1)) == 0
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package distributor
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyListStorage fails the first listing.
+type flakyListStorage struct {
+	*memory.ObjectStorage
+	listed int32
+}
+
+func (s *flakyListStorage) List(ctx context.Context, opts core.ListOptions) ([]core.ObjectInfo, error) {
+	if atomic.AddInt32(&s.listed, 1) == 1 {
+		return nil, errStorageFailed
+	}
+	return s.ObjectStorage.List(ctx, opts)
+}
+
+func TestRebalancer(t *testing.T) {
+	t.Run("when storage joins, objects are copied to their new owners", func(t *testing.T) {
+		storages := []*memory.ObjectStorage{memory.NewObjectStorage(), memory.NewObjectStorage(), memory.NewObjectStorage()}
+		distributor := NewObjectDistributor(newMemoryStorageSelector())
+		distributor.AddStorage("1", storages[0], 1)
+		distributor.AddStorage("2", storages[1], 1)
+
+		rebalancer := NewRebalancer(distributor, 0)
+		rebalancer.settleDelay = 0
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go rebalancer.Run(ctx)
+
+		objects := make(map[string][]byte)
+		for i := 0; i < 20; i++ {
+			objectID := fmt.Sprintf("object_%d", i)
+			objects[objectID] = []byte(objectID)
+			require.NoError(t, putObject(distributor, objectID, objects[objectID]))
+		}
+
+		newStorage := storages[2]
+		distributor.AddStorage("3", newStorage, 1)
+
+		assert.Eventually(t, func() bool {
+			progress := rebalancer.Progress()
+			return !progress.FinishedAt.IsZero() && !progress.Running
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// The modulo based selector moves objects between old storages too, so copies land everywhere.
+		progress := rebalancer.Progress()
+		assert.GreaterOrEqual(t, progress.ScannedObjects, len(objects))
+		assert.NotZero(t, newStorage.ObjectCount())
+		assert.NotZero(t, progress.CopiedObjects)
+		assert.Zero(t, progress.FailedObjects)
+
+		for objectID, blob := range objects {
+			actualObject, err := getObject(distributor, objectID)
+			assert.NoError(t, err)
+			assert.Equal(t, blob, actualObject)
+		}
+
+		t.Run("moved objects are only kept by their new owners", func(t *testing.T) {
+			assert.Equal(t, progress.CopiedObjects, progress.DeletedObjects)
+			count := 0
+			for _, storage := range storages {
+				count += storage.ObjectCount()
+			}
+			assert.Equal(t, len(objects), count)
+		})
+	})
+
+	t.Run("when listing a storage fails, its objects are moved by the next run", func(t *testing.T) {
+		source, target := memory.NewObjectStorage(), memory.NewObjectStorage()
+		distributor := NewObjectDistributor(newMemoryStorageSelector())
+		distributor.AddStorage("1", &flakyListStorage{ObjectStorage: source}, 1)
+
+		rebalancer := NewRebalancer(distributor, 0)
+		rebalancer.settleDelay = 0
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go rebalancer.Run(ctx)
+
+		const objectCount = 20
+		for i := 0; i < objectCount; i++ {
+			objectID := fmt.Sprintf("object_%d", i)
+			require.NoError(t, putObject(distributor, objectID, []byte(objectID)))
+		}
+		distributor.AddStorage("2", target, 1)
+
+		assert.Eventually(t, func() bool {
+			return target.ObjectCount() > 0 && source.ObjectCount()+target.ObjectCount() == objectCount
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("when ring was empty, nothing is rebalanced", func(t *testing.T) {
+		distributor := NewObjectDistributor(newMemoryStorageSelector())
+		rebalancer := NewRebalancer(distributor, 0)
+
+		distributor.AddStorage("1", memory.NewObjectStorage(), 1)
+
+		assert.True(t, rebalancer.rebalance(context.Background()))
+		assert.True(t, rebalancer.Progress().StartedAt.IsZero())
+	})
+
+	t.Run("when rate changes, throttling follows it", func(t *testing.T) {
+		rebalancer := NewRebalancer(NewObjectDistributor(newMemoryStorageSelector()), 1)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		lastCopy := time.Now()
+		assert.False(t, rebalancer.throttle(ctx, &lastCopy), "a copy within a second of the last one waits")
+
+		rebalancer.SetObjectsPerSecond(0)
+		assert.True(t, rebalancer.throttle(ctx, &lastCopy), "without a rate copies don't wait")
+	})
+}
@@ -0,0 +1,96 @@
+package distributor
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+)
+
+const replicationBufferSize = 32 << 10
+
+type replicaResult struct {
+	storageID string
+	info      core.ObjectInfo
+	err       error
+}
+
+// putReplicated streams the object to all replicas at once, so the body is read only once
+// and never held in memory as a whole. Replicas failing midway are dropped from the stream.
+// The info of the first acknowledging replica in placement order is returned.
+func (d *ObjectDistributor) putReplicated(ctx context.Context, objectID string, replicas []replica, r io.Reader, size int64, metadata core.ObjectMetadata) (core.ObjectInfo, error) {
+	writers := make([]*io.PipeWriter, len(replicas))
+	results := make(chan replicaResult, len(replicas))
+	for i, rep := range replicas {
+		pr, pw := io.Pipe()
+		writers[i] = pw
+
+		go func(rep replica, pr *io.PipeReader) {
+			info, err := rep.storage.Put(ctx, objectID, pr, size, metadata)
+			// Unblocks the fan out if the storage stopped reading before the end of the body.
+			pr.CloseWithError(fmt.Errorf("replica '%s' closed", rep.id))
+			results <- replicaResult{storageID: rep.id, info: info, err: err}
+		}(rep, pr)
+	}
+
+	copyErr := fanOut(r, writers)
+	for _, w := range writers {
+		if w != nil {
+			w.CloseWithError(copyErr)
+		}
+	}
+
+	acknowledged := make(map[string]core.ObjectInfo, len(replicas))
+	var lastErr error
+	for range replicas {
+		res := <-results
+		if res.err != nil {
+			logReplicaError(objectID, res.storageID, res.err, "putting object replica")
+			lastErr = fmt.Errorf("putting object to '%s' storage: %w", res.storageID, res.err)
+			continue
+		}
+		acknowledged[res.storageID] = res.info
+	}
+	if copyErr != nil {
+		return core.ObjectInfo{}, fmt.Errorf("reading object: %w", copyErr)
+	}
+	if len(acknowledged) < d.writeQuorum {
+		return core.ObjectInfo{}, fmt.Errorf("%w: %d of %d replicas acknowledged, %d required: %v", core.ErrQuorumNotReached, len(acknowledged), len(replicas), d.writeQuorum, lastErr)
+	}
+
+	for _, rep := range replicas {
+		if info, ok := acknowledged[rep.id]; ok {
+			return info, nil
+		}
+	}
+	return core.ObjectInfo{}, nil
+}
+
+// fanOut copies r into every writer, dropping writers which fail. It stops early once all writers are gone.
+func fanOut(r io.Reader, writers []*io.PipeWriter) error {
+	live := len(writers)
+	buf := make([]byte, replicationBufferSize)
+	for live > 0 {
+		n, err := r.Read(buf)
+		if n > 0 {
+			for i, w := range writers {
+				if w == nil {
+					continue
+				}
+				if _, werr := w.Write(buf[:n]); werr != nil {
+					w.Close()
+					writers[i] = nil
+					live--
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package distributor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errStorageFailed = errors.New("storage failed")
+
+// failingStorage emulates a storage node which died, it reads part of the body before failing.
+type failingStorage struct{}
+
+func (failingStorage) Put(ctx context.Context, objectID string, r io.Reader, size int64, metadata core.ObjectMetadata) (core.ObjectInfo, error) {
+	_, _ = r.Read(make([]byte, 1))
+	return core.ObjectInfo{}, errStorageFailed
+}
+
+func (failingStorage) Get(ctx context.Context, objectID string, opts core.GetOptions) (io.ReadCloser, core.ObjectInfo, error) {
+	return nil, core.ObjectInfo{}, errStorageFailed
+}
+
+func (failingStorage) Stat(ctx context.Context, objectID string) (core.ObjectInfo, error) {
+	return core.ObjectInfo{}, errStorageFailed
+}
+
+func (failingStorage) Delete(ctx context.Context, objectID string) error {
+	return errStorageFailed
+}
+
+func (failingStorage) List(ctx context.Context, opts core.ListOptions) ([]core.ObjectInfo, error) {
+	return nil, errStorageFailed
+}
+
+func TestObjectDistributorReplication(t *testing.T) {
+	const objectID = "object_id"
+	blob := []byte("Hello replicas")
+
+	t.Run("when replicated, object is put in every replica", func(t *testing.T) {
+		distributor := NewObjectDistributor(newMemoryStorageSelector(), WithReplication(3, 2))
+		memoryStorages := []*memory.ObjectStorage{memory.NewObjectStorage(), memory.NewObjectStorage(), memory.NewObjectStorage()}
+		for i, storage := range memoryStorages {
+			distributor.AddStorage(string(rune('1'+i)), storage, 1)
+		}
+
+		err := putObject(distributor, objectID, blob)
+		require.NoError(t, err)
+
+		for _, storage := range memoryStorages {
+			assert.Equal(t, 1, storage.ObjectCount())
+		}
+
+		actualObject, err := getObject(distributor, objectID)
+		assert.NoError(t, err)
+		assert.Equal(t, blob, actualObject)
+
+		t.Run("when deleted, object is removed from every replica", func(t *testing.T) {
+			err := distributor.DeleteObject(context.TODO(), objectID)
+			require.NoError(t, err)
+
+			for _, storage := range memoryStorages {
+				assert.Equal(t, 0, storage.ObjectCount())
+			}
+		})
+	})
+
+	t.Run("when a replica fails, write quorum is still reached and object can be read", func(t *testing.T) {
+		distributor := NewObjectDistributor(newMemoryStorageSelector(), WithReplication(3, 2))
+		distributor.AddStorage("1", memory.NewObjectStorage(), 1)
+		distributor.AddStorage("2", failingStorage{}, 1)
+		distributor.AddStorage("3", memory.NewObjectStorage(), 1)
+
+		err := putObject(distributor, objectID, blob)
+		require.NoError(t, err)
+
+		actualObject, err := getObject(distributor, objectID)
+		assert.NoError(t, err)
+		assert.Equal(t, blob, actualObject)
+	})
+
+	t.Run("when too many replicas fail, write quorum is not reached", func(t *testing.T) {
+		distributor := NewObjectDistributor(newMemoryStorageSelector(), WithReplication(3, 2))
+		distributor.AddStorage("1", memory.NewObjectStorage(), 1)
+		distributor.AddStorage("2", failingStorage{}, 1)
+		distributor.AddStorage("3", failingStorage{}, 1)
+
+		err := putObject(distributor, objectID, blob)
+		assert.ErrorIs(t, err, core.ErrQuorumNotReached)
+	})
+
+	t.Run("when fewer storages than replicas exist, all of them are used", func(t *testing.T) {
+		distributor := NewObjectDistributor(newMemoryStorageSelector(), WithReplication(3, 1))
+		storage := memory.NewObjectStorage()
+		distributor.AddStorage("1", storage, 1)
+
+		err := putObject(distributor, objectID, blob)
+		require.NoError(t, err)
+		assert.Equal(t, 1, storage.ObjectCount())
+	})
+}
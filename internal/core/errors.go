@@ -3,3 +3,11 @@ package core
 import "errors"
 
 var ErrNotFound = errors.New("not found")
+
+var ErrQuorumNotReached = errors.New("quorum not reached")
+
+var ErrInvalidRange = errors.New("invalid range")
+
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+var ErrInvalidPart = errors.New("invalid part")
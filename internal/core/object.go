@@ -0,0 +1,70 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// InternalObjectPrefix marks objects the service keeps for its own state, they are hidden from clients.
+const InternalObjectPrefix = ".internal/"
+
+var objectIDPattern = regexp.MustCompile(`^[a-zA-Z0-9]{1,32}$`)
+
+func IsInternalObject(objectID string) bool {
+	return strings.HasPrefix(objectID, InternalObjectPrefix)
+}
+
+// ValidObjectID reports whether clients may use the object ID, internal objects never match.
+func ValidObjectID(objectID string) bool {
+	return objectIDPattern.MatchString(objectID)
+}
+
+type ObjectInfo struct {
+	ID           string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	Metadata     ObjectMetadata
+}
+
+// ObjectMetadata is stored along with the object and returned on reads.
+type ObjectMetadata struct {
+	ContentType        string
+	ContentEncoding    string
+	ContentDisposition string
+	// UserMetadata is keyed by canonical header keys without any prefix.
+	UserMetadata map[string]string
+}
+
+// ByteRange is an inclusive range of object bytes.
+type ByteRange struct {
+	Start int64
+	End   int64
+}
+
+func (r ByteRange) Length() int64 {
+	return r.End - r.Start + 1
+}
+
+type GetOptions struct {
+	// Range limits the returned body to the range, the returned info still describes the whole object.
+	Range *ByteRange
+	// MatchETag fails the read with ErrPreconditionFailed unless the object still has the given ETag.
+	MatchETag string
+}
+
+// PartInfo describes a part of a multipart upload.
+type PartInfo struct {
+	PartNumber int
+	ETag       string
+	Size       int64
+}
+
+type ListOptions struct {
+	Prefix string
+	// StartAfter lists only objects whose ID sorts after it.
+	StartAfter string
+	// Limit caps the number of returned objects, 0 means no limit.
+	Limit int
+}
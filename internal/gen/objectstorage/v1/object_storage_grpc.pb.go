@@ -0,0 +1,323 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: objectstorage/v1/object_storage.proto
+
+package objectstoragev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ObjectStorageService_Put_FullMethodName    = "/objectstorage.v1.ObjectStorageService/Put"
+	ObjectStorageService_Get_FullMethodName    = "/objectstorage.v1.ObjectStorageService/Get"
+	ObjectStorageService_Head_FullMethodName   = "/objectstorage.v1.ObjectStorageService/Head"
+	ObjectStorageService_Delete_FullMethodName = "/objectstorage.v1.ObjectStorageService/Delete"
+	ObjectStorageService_List_FullMethodName   = "/objectstorage.v1.ObjectStorageService/List"
+)
+
+// ObjectStorageServiceClient is the client API for ObjectStorageService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ObjectStorageServiceClient interface {
+	// Put stores an object sent as a header message followed by data chunks.
+	Put(ctx context.Context, opts ...grpc.CallOption) (ObjectStorageService_PutClient, error)
+	// Get streams the object info followed by data chunks.
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (ObjectStorageService_GetClient, error)
+	Head(ctx context.Context, in *HeadRequest, opts ...grpc.CallOption) (*HeadResponse, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error)
+}
+
+type objectStorageServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewObjectStorageServiceClient(cc grpc.ClientConnInterface) ObjectStorageServiceClient {
+	return &objectStorageServiceClient{cc}
+}
+
+func (c *objectStorageServiceClient) Put(ctx context.Context, opts ...grpc.CallOption) (ObjectStorageService_PutClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ObjectStorageService_ServiceDesc.Streams[0], ObjectStorageService_Put_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &objectStorageServicePutClient{stream}
+	return x, nil
+}
+
+type ObjectStorageService_PutClient interface {
+	Send(*PutRequest) error
+	CloseAndRecv() (*PutResponse, error)
+	grpc.ClientStream
+}
+
+type objectStorageServicePutClient struct {
+	grpc.ClientStream
+}
+
+func (x *objectStorageServicePutClient) Send(m *PutRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *objectStorageServicePutClient) CloseAndRecv() (*PutResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PutResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *objectStorageServiceClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (ObjectStorageService_GetClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ObjectStorageService_ServiceDesc.Streams[1], ObjectStorageService_Get_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &objectStorageServiceGetClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ObjectStorageService_GetClient interface {
+	Recv() (*GetResponse, error)
+	grpc.ClientStream
+}
+
+type objectStorageServiceGetClient struct {
+	grpc.ClientStream
+}
+
+func (x *objectStorageServiceGetClient) Recv() (*GetResponse, error) {
+	m := new(GetResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *objectStorageServiceClient) Head(ctx context.Context, in *HeadRequest, opts ...grpc.CallOption) (*HeadResponse, error) {
+	out := new(HeadResponse)
+	err := c.cc.Invoke(ctx, ObjectStorageService_Head_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *objectStorageServiceClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, ObjectStorageService_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *objectStorageServiceClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListResponse, error) {
+	out := new(ListResponse)
+	err := c.cc.Invoke(ctx, ObjectStorageService_List_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ObjectStorageServiceServer is the server API for ObjectStorageService service.
+// All implementations must embed UnimplementedObjectStorageServiceServer
+// for forward compatibility
+type ObjectStorageServiceServer interface {
+	// Put stores an object sent as a header message followed by data chunks.
+	Put(ObjectStorageService_PutServer) error
+	// Get streams the object info followed by data chunks.
+	Get(*GetRequest, ObjectStorageService_GetServer) error
+	Head(context.Context, *HeadRequest) (*HeadResponse, error)
+	Delete(context.Context, *DeleteRequest) (*DeleteResponse, error)
+	List(context.Context, *ListRequest) (*ListResponse, error)
+	mustEmbedUnimplementedObjectStorageServiceServer()
+}
+
+// UnimplementedObjectStorageServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedObjectStorageServiceServer struct {
+}
+
+func (UnimplementedObjectStorageServiceServer) Put(ObjectStorageService_PutServer) error {
+	return status.Errorf(codes.Unimplemented, "method Put not implemented")
+}
+func (UnimplementedObjectStorageServiceServer) Get(*GetRequest, ObjectStorageService_GetServer) error {
+	return status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedObjectStorageServiceServer) Head(context.Context, *HeadRequest) (*HeadResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Head not implemented")
+}
+func (UnimplementedObjectStorageServiceServer) Delete(context.Context, *DeleteRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedObjectStorageServiceServer) List(context.Context, *ListRequest) (*ListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedObjectStorageServiceServer) mustEmbedUnimplementedObjectStorageServiceServer() {}
+
+// UnsafeObjectStorageServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ObjectStorageServiceServer will
+// result in compilation errors.
+type UnsafeObjectStorageServiceServer interface {
+	mustEmbedUnimplementedObjectStorageServiceServer()
+}
+
+func RegisterObjectStorageServiceServer(s grpc.ServiceRegistrar, srv ObjectStorageServiceServer) {
+	s.RegisterService(&ObjectStorageService_ServiceDesc, srv)
+}
+
+func _ObjectStorageService_Put_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ObjectStorageServiceServer).Put(&objectStorageServicePutServer{stream})
+}
+
+type ObjectStorageService_PutServer interface {
+	SendAndClose(*PutResponse) error
+	Recv() (*PutRequest, error)
+	grpc.ServerStream
+}
+
+type objectStorageServicePutServer struct {
+	grpc.ServerStream
+}
+
+func (x *objectStorageServicePutServer) SendAndClose(m *PutResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *objectStorageServicePutServer) Recv() (*PutRequest, error) {
+	m := new(PutRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ObjectStorageService_Get_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ObjectStorageServiceServer).Get(m, &objectStorageServiceGetServer{stream})
+}
+
+type ObjectStorageService_GetServer interface {
+	Send(*GetResponse) error
+	grpc.ServerStream
+}
+
+type objectStorageServiceGetServer struct {
+	grpc.ServerStream
+}
+
+func (x *objectStorageServiceGetServer) Send(m *GetResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ObjectStorageService_Head_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObjectStorageServiceServer).Head(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ObjectStorageService_Head_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObjectStorageServiceServer).Head(ctx, req.(*HeadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ObjectStorageService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObjectStorageServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ObjectStorageService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObjectStorageServiceServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ObjectStorageService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObjectStorageServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ObjectStorageService_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObjectStorageServiceServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ObjectStorageService_ServiceDesc is the grpc.ServiceDesc for ObjectStorageService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ObjectStorageService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "objectstorage.v1.ObjectStorageService",
+	HandlerType: (*ObjectStorageServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Head",
+			Handler:    _ObjectStorageService_Head_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _ObjectStorageService_Delete_Handler,
+		},
+		{
+			MethodName: "List",
+			Handler:    _ObjectStorageService_List_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Put",
+			Handler:       _ObjectStorageService_Put_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Get",
+			Handler:       _ObjectStorageService_Get_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "objectstorage/v1/object_storage.proto",
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+)
+
+// parseETags unquotes an If-Match or If-None-Match list. With strong comparison weak tags are kept
+// as they are, so they never match a stored ETag, with weak comparison their prefix is dropped.
+func parseETags(header string, weak bool) []string {
+	etags := make([]string, 0)
+	for _, etag := range strings.Split(header, ",") {
+		etag = textproto.TrimString(etag)
+		switch {
+		case etag == "":
+			continue
+		case etag == "*":
+		case strings.HasPrefix(etag, "W/"):
+			if !weak {
+				break
+			}
+			etag = strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+		default:
+			etag = strings.Trim(etag, `"`)
+		}
+		etags = append(etags, etag)
+	}
+	return etags
+}
+
+func putOptions(r *http.Request) (distributor.PutOptions, error) {
+	metadata, err := parseMetadata(r)
+	if err != nil {
+		return distributor.PutOptions{}, err
+	}
+
+	return distributor.PutOptions{
+		Metadata:    metadata,
+		IfMatch:     parseETags(r.Header.Get("If-Match"), false),
+		IfNoneMatch: parseETags(r.Header.Get("If-None-Match"), true),
+	}, nil
+}
+
+// hasReadConditions reports whether the read has to be checked with notModified before it is served.
+func hasReadConditions(r *http.Request) bool {
+	return r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != ""
+}
+
+// notModified evaluates If-None-Match and, only without it, If-Modified-Since against the object.
+func notModified(r *http.Request, info core.ObjectInfo) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		for _, etag := range parseETags(header, true) {
+			if etag == "*" || (info.ETag != "" && etag == info.ETag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || info.LastModified.IsZero() {
+		return false
+	}
+	return !info.LastModified.Truncate(time.Second).After(since)
+}
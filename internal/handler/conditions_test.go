@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseETags(t *testing.T) {
+	assert.Equal(t, []string{"*"}, parseETags("*", false))
+	assert.Equal(t, []string{"a", "b"}, parseETags(`"a", "b"`, false))
+	assert.Equal(t, []string{`W/"a"`, "b"}, parseETags(`W/"a", "b"`, false))
+	assert.Equal(t, []string{"a", "b"}, parseETags(`W/"a", "b"`, true))
+	assert.Empty(t, parseETags("", false))
+}
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2023, 5, 1, 12, 0, 0, 500, time.UTC)
+	info := core.ObjectInfo{ETag: "abc", LastModified: lastModified}
+
+	request := func(headers map[string]string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, "/object/id", nil)
+		for name, value := range headers {
+			r.Header.Set(name, value)
+		}
+		return r
+	}
+
+	assert.True(t, notModified(request(map[string]string{"If-None-Match": `"abc"`}), info))
+	assert.True(t, notModified(request(map[string]string{"If-None-Match": `W/"abc"`}), info))
+	assert.False(t, notModified(request(map[string]string{"If-None-Match": `"other"`}), info))
+	assert.True(t, notModified(request(map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)}), info))
+	assert.False(t, notModified(request(map[string]string{"If-Modified-Since": lastModified.Add(-time.Hour).Format(http.TimeFormat)}), info))
+
+	t.Run("when If-None-Match is present, If-Modified-Since is ignored", func(t *testing.T) {
+		assert.False(t, notModified(request(map[string]string{
+			"If-None-Match":     `"other"`,
+			"If-Modified-Since": lastModified.Format(http.TimeFormat),
+		}), info))
+	})
+}
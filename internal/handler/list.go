@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+type listObjectsResponse struct {
+	Objects []listedObject `json:"objects"`
+	// NextCursor continues the listing, it is empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+type listedObject struct {
+	ID           string    `json:"id"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+func listObjects(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		limit := defaultListLimit
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxListLimit {
+				http.Error(w, "limit has to be between 1 and 1000", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+
+		startAfter, err := decodeCursor(query.Get("cursor"))
+		if err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+
+		objects, truncated, err := objectDistributor.ListObjects(r.Context(), core.ListOptions{
+			Prefix:     query.Get("prefix"),
+			StartAfter: startAfter,
+			Limit:      limit,
+		})
+		if err != nil {
+			logrus.WithError(err).Error("listing objects")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		response := listObjectsResponse{
+			Objects: make([]listedObject, 0, len(objects)),
+		}
+		for _, info := range objects {
+			response.Objects = append(response.Objects, listedObject{
+				ID:           info.ID,
+				Size:         info.Size,
+				ETag:         info.ETag,
+				LastModified: info.LastModified.UTC(),
+			})
+		}
+		if truncated {
+			response.NextCursor = encodeCursor(objects[len(objects)-1].ID)
+		}
+
+		httputil.WriteJSON(w, http.StatusOK, response)
+	}
+}
+
+// Cursors are opaque to clients, they only carry the last listed ID.
+func encodeCursor(lastID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(lastID))
+}
+
+func decodeCursor(cursor string) (string, error) {
+	lastID, err := base64.RawURLEncoding.DecodeString(cursor)
+	return string(lastID), err
+}
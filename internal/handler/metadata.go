@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+)
+
+const (
+	userMetadataPrefix = "X-Object-Meta-"
+	defaultContentType = "application/octet-stream"
+)
+
+// parseMetadata collects the metadata stored along with the object from the request headers.
+func parseMetadata(r *http.Request) (core.ObjectMetadata, error) {
+	return httputil.ParseMetadata(r.Header, userMetadataPrefix)
+}
+
+func writeMetadataHeaders(w http.ResponseWriter, metadata core.ObjectMetadata) {
+	contentType := metadata.ContentType
+	if contentType == "" {
+		// Without it net/http would sniff the body and guess.
+		contentType = defaultContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+	if metadata.ContentEncoding != "" {
+		w.Header().Set("Content-Encoding", metadata.ContentEncoding)
+	}
+	if metadata.ContentDisposition != "" {
+		w.Header().Set("Content-Disposition", metadata.ContentDisposition)
+	}
+	for name, value := range metadata.UserMetadata {
+		w.Header().Set(userMetadataPrefix+name, value)
+	}
+}
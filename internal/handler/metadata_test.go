@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMetadata(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/object/id", nil)
+	r.Header.Set("Content-Type", "text/csv")
+	r.Header.Set("Content-Disposition", "inline")
+	r.Header.Set("x-object-meta-owner", "team-a")
+	r.Header.Set("X-Other", "ignored")
+
+	metadata, err := parseMetadata(r)
+	require.NoError(t, err)
+	assert.Equal(t, core.ObjectMetadata{
+		ContentType:        "text/csv",
+		ContentDisposition: "inline",
+		UserMetadata:       map[string]string{"Owner": "team-a"},
+	}, metadata)
+
+	t.Run("when user metadata is too large, should fail", func(t *testing.T) {
+		r.Header.Set("X-Object-Meta-Large", strings.Repeat("a", httputil.MaxUserMetadataSize))
+		_, err := parseMetadata(r)
+		assert.Equal(t, httputil.ErrMetadataTooLarge, err)
+	})
+}
+
+func TestWriteMetadataHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeMetadataHeaders(w, core.ObjectMetadata{UserMetadata: map[string]string{"Owner": "team-a"}})
+
+	assert.Equal(t, defaultContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "team-a", w.Header().Get("X-Object-Meta-Owner"))
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
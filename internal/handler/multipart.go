@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+)
+
+const maxPartNumber = 10000
+
+// maxCompleteRequestSize bounds the complete request body, which lists at most maxPartNumber parts.
+const maxCompleteRequestSize = 1 << 20
+
+type initiateUploadResponse struct {
+	UploadID string `json:"uploadId"`
+}
+
+type uploadedPart struct {
+	PartNumber int    `json:"partNumber"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size,omitempty"`
+}
+
+type uploadPartsResponse struct {
+	Parts []uploadedPart `json:"parts"`
+}
+
+// completeUploadRequest lists the parts the object is assembled from, all uploaded parts are used when it's empty.
+type completeUploadRequest struct {
+	Parts []uploadedPart `json:"parts"`
+}
+
+func initiateUpload(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		objectID := mux.Vars(r)["id"]
+		metadata, err := parseMetadata(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		uploadID, err := objectDistributor.InitiateUpload(r.Context(), objectID, metadata)
+		if err != nil {
+			writeUploadError(w, objectID, err, "initiating upload")
+			return
+		}
+
+		httputil.WriteJSON(w, http.StatusCreated, initiateUploadResponse{UploadID: uploadID})
+	}
+}
+
+func putUploadPart(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		objectID := vars["id"]
+		partNumber, err := strconv.Atoi(vars["partNumber"])
+		if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+			http.Error(w, "part number has to be between 1 and 10000", http.StatusBadRequest)
+			return
+		}
+		// Parts are stored as they are uploaded, which requires their size upfront.
+		if r.ContentLength < 0 {
+			w.WriteHeader(http.StatusLengthRequired)
+			return
+		}
+
+		part, err := objectDistributor.PutUploadPart(r.Context(), objectID, vars["uploadID"], partNumber, r.Body, r.ContentLength)
+		if err != nil {
+			writeUploadError(w, objectID, err, "putting upload part")
+			return
+		}
+
+		w.Header().Set("ETag", strconv.Quote(part.ETag))
+		httputil.WriteJSON(w, http.StatusOK, toUploadedPart(part))
+	}
+}
+
+func listUploadParts(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		objectID := vars["id"]
+
+		parts, err := objectDistributor.ListUploadParts(r.Context(), objectID, vars["uploadID"])
+		if err != nil {
+			writeUploadError(w, objectID, err, "listing upload parts")
+			return
+		}
+
+		response := uploadPartsResponse{
+			Parts: make([]uploadedPart, 0, len(parts)),
+		}
+		for _, part := range parts {
+			response.Parts = append(response.Parts, toUploadedPart(part))
+		}
+		httputil.WriteJSON(w, http.StatusOK, response)
+	}
+}
+
+func completeUpload(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		objectID := vars["id"]
+
+		var request completeUploadRequest
+		err := json.NewDecoder(io.LimitReader(r.Body, maxCompleteRequestSize)).Decode(&request)
+		if err != nil && err != io.EOF {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		parts := make([]core.PartInfo, 0, len(request.Parts))
+		for _, part := range request.Parts {
+			parts = append(parts, core.PartInfo{PartNumber: part.PartNumber, ETag: part.ETag})
+		}
+
+		info, err := objectDistributor.CompleteUpload(r.Context(), objectID, vars["uploadID"], parts)
+		if err != nil {
+			writeUploadError(w, objectID, err, "completing upload")
+			return
+		}
+
+		w.Header().Set("ETag", strconv.Quote(info.ETag))
+		httputil.WriteJSON(w, http.StatusOK, listedObject{
+			ID:           info.ID,
+			Size:         info.Size,
+			ETag:         info.ETag,
+			LastModified: info.LastModified.UTC(),
+		})
+	}
+}
+
+func abortUpload(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		objectID := vars["id"]
+
+		if err := objectDistributor.AbortUpload(r.Context(), objectID, vars["uploadID"]); err != nil {
+			writeUploadError(w, objectID, err, "aborting upload")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeUploadError(w http.ResponseWriter, objectID string, err error, msg string) {
+	switch {
+	case err == core.ErrNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, core.ErrInvalidPart):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err == distributor.ErrMultipartNotSupported:
+		w.WriteHeader(http.StatusNotImplemented)
+	default:
+		logrus.WithFields(logrus.Fields{
+			"id": objectID,
+		}).WithError(err).Error(msg)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func toUploadedPart(part core.PartInfo) uploadedPart {
+	return uploadedPart{
+		PartNumber: part.PartNumber,
+		ETag:       part.ETag,
+		Size:       part.Size,
+	}
+}
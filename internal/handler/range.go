@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+)
+
+// maxRanges bounds the ranges served for a request, each of them costs a separate read.
+const maxRanges = 64
+
+var (
+	errMalformedRange = errors.New("malformed range")
+	errNoOverlap      = errors.New("range does not overlap the object")
+)
+
+// parseRange resolves a "bytes=" Range header against an object of the given size.
+// A nil result without an error means the header should be ignored and the whole object served.
+func parseRange(header string, size int64) ([]core.ByteRange, error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, errMalformedRange
+	}
+
+	var ranges []core.ByteRange
+	noOverlap := false
+	for _, spec := range strings.Split(header[len(prefix):], ",") {
+		spec = textproto.TrimString(spec)
+		if spec == "" {
+			continue
+		}
+		i := strings.Index(spec, "-")
+		if i < 0 {
+			return nil, errMalformedRange
+		}
+		first, last := textproto.TrimString(spec[:i]), textproto.TrimString(spec[i+1:])
+
+		var r core.ByteRange
+		if first == "" {
+			// Suffix range, the last N bytes.
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, errMalformedRange
+			}
+			if n == 0 || size == 0 {
+				noOverlap = true
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r = core.ByteRange{Start: size - n, End: size - 1}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, errMalformedRange
+			}
+			if start >= size {
+				noOverlap = true
+				continue
+			}
+			r = core.ByteRange{Start: start, End: size - 1}
+			if last != "" {
+				end, err := strconv.ParseInt(last, 10, 64)
+				if err != nil || end < start {
+					return nil, errMalformedRange
+				}
+				if end < size-1 {
+					r.End = end
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		if noOverlap {
+			return nil, errNoOverlap
+		}
+		return nil, errMalformedRange
+	}
+
+	if len(ranges) > maxRanges {
+		return nil, nil
+	}
+
+	// Same as net/http, requests asking for more than the whole object get the whole object.
+	var total int64
+	for _, r := range ranges {
+		total += r.Length()
+	}
+	if total > size {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// ifRangeMatches reports whether a Range header may be honoured given the request's If-Range validator.
+func ifRangeMatches(r *http.Request, info core.ObjectInfo) bool {
+	validator := textproto.TrimString(r.Header.Get("If-Range"))
+	if validator == "" {
+		return true
+	}
+	if strings.HasPrefix(validator, `"`) {
+		// Only strong comparison is allowed for If-Range.
+		return info.ETag != "" && validator == strconv.Quote(info.ETag)
+	}
+	if strings.HasPrefix(validator, "W/") {
+		return false
+	}
+	t, err := http.ParseTime(validator)
+	if err != nil || info.LastModified.IsZero() {
+		return false
+	}
+	return info.LastModified.Truncate(time.Second).Equal(t)
+}
+
+// serveRanges writes a 206 response with either a single range or a multipart/byteranges body.
+func serveRanges(w http.ResponseWriter, r *http.Request, objectDistributor *distributor.ObjectDistributor, objectID string, opts distributor.GetOptions, info core.ObjectInfo, ranges []core.ByteRange) {
+	if len(ranges) == 1 {
+		rng := ranges[0]
+		opts.Range = &rng
+		body, _, err := objectDistributor.GetObject(r.Context(), objectID, opts)
+		if err != nil {
+			if errors.Is(err, core.ErrInvalidRange) {
+				// The object shrank since it was stat'ed.
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+			}
+			writeReadError(w, objectID, err, "getting object range")
+			return
+		}
+		defer body.Close()
+
+		writeObjectInfoHeaders(w, info)
+		w.Header().Set("Content-Length", strconv.FormatInt(rng.Length(), 10))
+		w.Header().Set("Content-Range", contentRange(rng, info.Size))
+		w.WriteHeader(http.StatusPartialContent)
+		httputil.CopyBody(w, body, objectID)
+		return
+	}
+
+	mw := multipart.NewWriter(w)
+	writeObjectInfoHeaders(w, info)
+	// The multipart body length is not known upfront, so it is sent chunked.
+	w.Header().Del("Content-Length")
+	// Parts carry ranges of the encoded object, the multipart body itself isn't encoded.
+	w.Header().Del("Content-Encoding")
+	w.Header().Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+	w.WriteHeader(http.StatusPartialContent)
+
+	for _, rng := range ranges {
+		rng := rng
+		opts.Range = &rng
+		if err := writeRangePart(r, mw, objectDistributor, objectID, opts, info); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"id": objectID,
+			}).WithError(err).Error("writing range part")
+			return
+		}
+	}
+	if err := mw.Close(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"id": objectID,
+		}).WithError(err).Error("writing response")
+	}
+}
+
+func writeRangePart(r *http.Request, mw *multipart.Writer, objectDistributor *distributor.ObjectDistributor, objectID string, opts distributor.GetOptions, info core.ObjectInfo) error {
+	body, _, err := objectDistributor.GetObject(r.Context(), objectID, opts)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	contentType := info.Metadata.ContentType
+	if contentType == "" {
+		contentType = defaultContentType
+	}
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":  {contentType},
+		"Content-Range": {contentRange(*opts.Range, info.Size)},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, body)
+	return err
+}
+
+func contentRange(r core.ByteRange, size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.End, size)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	t.Run("single and suffix ranges should be resolved against the size", func(t *testing.T) {
+		ranges, err := parseRange("bytes=0-4", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []core.ByteRange{{Start: 0, End: 4}}, ranges)
+
+		ranges, err = parseRange("bytes=7-", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []core.ByteRange{{Start: 7, End: 9}}, ranges)
+
+		ranges, err = parseRange("bytes=-3", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []core.ByteRange{{Start: 7, End: 9}}, ranges)
+
+		ranges, err = parseRange("bytes=5-100", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []core.ByteRange{{Start: 5, End: 9}}, ranges)
+	})
+
+	t.Run("multiple ranges should be kept in request order", func(t *testing.T) {
+		ranges, err := parseRange("bytes=6-7, 0-1", 10)
+		require.NoError(t, err)
+		assert.Equal(t, []core.ByteRange{{Start: 6, End: 7}, {Start: 0, End: 1}}, ranges)
+	})
+
+	t.Run("when no range overlaps the object, should return no overlap", func(t *testing.T) {
+		_, err := parseRange("bytes=10-20", 10)
+		assert.Equal(t, errNoOverlap, err)
+
+		_, err = parseRange("bytes=-5", 0)
+		assert.Equal(t, errNoOverlap, err)
+	})
+
+	t.Run("when range is malformed, should return malformed range", func(t *testing.T) {
+		for _, header := range []string{"items=0-1", "bytes=a-b", "bytes=5-1", "bytes=1"} {
+			_, err := parseRange(header, 10)
+			assert.Equal(t, errMalformedRange, err, header)
+		}
+	})
+
+	t.Run("when ranges exceed the object size, whole object should be served", func(t *testing.T) {
+		ranges, err := parseRange("bytes=0-9,0-9", 10)
+		assert.NoError(t, err)
+		assert.Nil(t, ranges)
+	})
+
+	t.Run("when there are too many ranges, whole object should be served", func(t *testing.T) {
+		specs := make([]string, 0, maxRanges+1)
+		for i := 0; i <= maxRanges; i++ {
+			specs = append(specs, fmt.Sprintf("%d-%d", i, i))
+		}
+		ranges, err := parseRange("bytes="+strings.Join(specs, ","), 1000)
+		assert.NoError(t, err)
+		assert.Nil(t, ranges)
+	})
+}
+
+func TestIfRangeMatches(t *testing.T) {
+	lastModified := time.Date(2023, 5, 1, 12, 0, 0, 500, time.UTC)
+	info := core.ObjectInfo{ETag: "abc", LastModified: lastModified}
+
+	request := func(validator string) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, "/object/id", nil)
+		r.Header.Set("If-Range", validator)
+		return r
+	}
+
+	assert.True(t, ifRangeMatches(request(""), info))
+	assert.True(t, ifRangeMatches(request(`"abc"`), info))
+	assert.False(t, ifRangeMatches(request(`"other"`), info))
+	assert.False(t, ifRangeMatches(request(`W/"abc"`), info))
+	assert.True(t, ifRangeMatches(request(lastModified.Format(http.TimeFormat)), info))
+	assert.False(t, ifRangeMatches(request(lastModified.Add(-time.Hour).Format(http.TimeFormat)), info))
+}
+
+func TestGetObjectRange(t *testing.T) {
+	objectDistributor := distributor.NewObjectDistributor(util.NewConsistentHashStorageSelector(), distributor.WithReplication(2, 2))
+	for _, storageID := range []string{"a", "b", "c"} {
+		objectDistributor.AddStorage(storageID, memory.NewObjectStorage(), 1)
+	}
+	_, err := objectDistributor.PutObject(context.Background(), "object", strings.NewReader("0123456789"), 10, distributor.PutOptions{})
+	require.NoError(t, err)
+	router := Router(objectDistributor)
+
+	get := func(rangeHeader string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/object/object", nil)
+		r.Header.Set("Range", rangeHeader)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("when range is satisfiable, it should be served", func(t *testing.T) {
+		w := get("bytes=2-5")
+		assert.Equal(t, http.StatusPartialContent, w.Code)
+		assert.Equal(t, "2345", w.Body.String())
+		assert.Equal(t, "bytes 2-5/10", w.Header().Get("Content-Range"))
+	})
+
+	t.Run("when range is malformed, whole object should be served", func(t *testing.T) {
+		w := get("bytes=5-1")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "0123456789", w.Body.String())
+	})
+
+	t.Run("when range does not overlap, should return range not satisfiable", func(t *testing.T) {
+		w := get("bytes=20-30")
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, w.Code)
+		assert.Equal(t, "bytes */10", w.Header().Get("Content-Range"))
+	})
+}
@@ -1,36 +1,75 @@
 package handler
 
 import (
-	"io"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"github.com/spacelift-io/homework-object-storage/internal/core"
 	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+	"github.com/spacelift-io/homework-object-storage/internal/tracing"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// consistencyLevelHeader selects how many replicas are compared on reads: one, quorum or all.
+const consistencyLevelHeader = "X-Consistency-Level"
+
 func Router(objectDistributor *distributor.ObjectDistributor) http.Handler {
 	r := mux.NewRouter()
+	// Spans continue traces of clients sending a W3C traceparent header.
+	r.Use(otelmux.Middleware(tracing.ServiceName), traceObjectID)
 	r.HandleFunc("/object/{id:[a-zA-Z0-9]{1,32}}", putObject(objectDistributor)).Methods(http.MethodPut)
 	r.HandleFunc("/object/{id:[a-zA-Z0-9]{1,32}}", getObject(objectDistributor)).Methods(http.MethodGet)
+	r.HandleFunc("/object/{id:[a-zA-Z0-9]{1,32}}", headObject(objectDistributor)).Methods(http.MethodHead)
+	r.HandleFunc("/object/{id:[a-zA-Z0-9]{1,32}}", deleteObject(objectDistributor)).Methods(http.MethodDelete)
+	r.HandleFunc("/object/{id:[a-zA-Z0-9]{1,32}}/uploads", initiateUpload(objectDistributor)).Methods(http.MethodPost)
+	r.HandleFunc("/object/{id:[a-zA-Z0-9]{1,32}}/uploads/{uploadID}", listUploadParts(objectDistributor)).Methods(http.MethodGet)
+	r.HandleFunc("/object/{id:[a-zA-Z0-9]{1,32}}/uploads/{uploadID}", abortUpload(objectDistributor)).Methods(http.MethodDelete)
+	r.HandleFunc("/object/{id:[a-zA-Z0-9]{1,32}}/uploads/{uploadID}/parts/{partNumber:[0-9]+}", putUploadPart(objectDistributor)).Methods(http.MethodPut)
+	r.HandleFunc("/object/{id:[a-zA-Z0-9]{1,32}}/uploads/{uploadID}/complete", completeUpload(objectDistributor)).Methods(http.MethodPost)
+	r.HandleFunc("/objects", listObjects(objectDistributor)).Methods(http.MethodGet)
 	return r
 }
 
+// traceObjectID adds the requested object ID to the request span.
+func traceObjectID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if objectID, ok := mux.Vars(r)["id"]; ok {
+			trace.SpanFromContext(r.Context()).SetAttributes(tracing.ObjectID(objectID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func putObject(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		objectID := mux.Vars(r)["id"]
-
-		bodyBytes, err := io.ReadAll(r.Body)
+		opts, err := putOptions(r)
 		if err != nil {
-			logrus.WithFields(logrus.Fields{
-				"id": objectID,
-			}).WithError(err).Error("reading request body")
-			w.WriteHeader(http.StatusInternalServerError)
+			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 
-		if err := objectDistributor.PutObject(r.Context(), objectID, bodyBytes); err != nil {
+		// ContentLength is -1 for chunked requests, storages handle unknown sizes on their own.
+		_, err = objectDistributor.PutObject(r.Context(), objectID, r.Body, r.ContentLength, opts)
+		switch {
+		case err == nil:
+		// Ok
+		case err == core.ErrPreconditionFailed:
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		case errors.Is(err, core.ErrQuorumNotReached):
+			logrus.WithFields(logrus.Fields{
+				"id": objectID,
+			}).WithError(err).Error("putting object")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		default:
 			logrus.WithFields(logrus.Fields{
 				"id": objectID,
 			}).WithError(err).Error("putting object")
@@ -45,27 +84,134 @@ func putObject(objectDistributor *distributor.ObjectDistributor) func(w http.Res
 func getObject(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		objectID := mux.Vars(r)["id"]
-		object, err := objectDistributor.GetObject(r.Context(), objectID)
-		switch err {
+		consistency, err := distributor.ParseConsistencyLevel(r.Header.Get(consistencyLevelHeader))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		opts := distributor.GetOptions{Consistency: consistency}
+
+		if rangeHeader := r.Header.Get("Range"); rangeHeader != "" || hasReadConditions(r) {
+			info, err := objectDistributor.StatObject(r.Context(), objectID, opts)
+			if err != nil {
+				writeReadError(w, objectID, err, "getting object info")
+				return
+			}
+			if notModified(r, info) {
+				writeNotModified(w, info)
+				return
+			}
+			if rangeHeader != "" && ifRangeMatches(r, info) {
+				ranges, err := parseRange(rangeHeader, info.Size)
+				if err == errNoOverlap {
+					w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size))
+					w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+					return
+				}
+				// Malformed ranges are ignored and the whole object is served, as RFC 9110 allows.
+				if err == nil && ranges != nil {
+					// Every range is read from the version the headers describe.
+					opts.MatchETag = info.ETag
+					serveRanges(w, r, objectDistributor, objectID, opts, info, ranges)
+					return
+				}
+			}
+		}
+
+		body, info, err := objectDistributor.GetObject(r.Context(), objectID, opts)
+		if err != nil {
+			writeReadError(w, objectID, err, "getting object")
+			return
+		}
+		defer body.Close()
+
+		writeObjectInfoHeaders(w, info)
+		w.WriteHeader(http.StatusOK)
+
+		httputil.CopyBody(w, body, objectID)
+	}
+}
+
+func headObject(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		objectID := mux.Vars(r)["id"]
+		consistency, err := distributor.ParseConsistencyLevel(r.Header.Get(consistencyLevelHeader))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		info, err := objectDistributor.StatObject(r.Context(), objectID, distributor.GetOptions{Consistency: consistency})
+		if err != nil {
+			writeReadError(w, objectID, err, "getting object info")
+			return
+		}
+		if notModified(r, info) {
+			writeNotModified(w, info)
+			return
+		}
+
+		writeObjectInfoHeaders(w, info)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func deleteObject(objectDistributor *distributor.ObjectDistributor) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		objectID := mux.Vars(r)["id"]
+		switch err := objectDistributor.DeleteObject(r.Context(), objectID); err {
 		case nil:
-		// Ok
+			w.WriteHeader(http.StatusNoContent)
 		case core.ErrNotFound:
 			w.WriteHeader(http.StatusNotFound)
-			return
 		default:
 			logrus.WithFields(logrus.Fields{
 				"id": objectID,
-			}).WithError(err).Error("getting object")
+			}).WithError(err).Error("deleting object")
 			w.WriteHeader(http.StatusInternalServerError)
-			return
 		}
+	}
+}
 
-		if _, err := w.Write(object); err != nil {
-			logrus.WithFields(logrus.Fields{
-				"id": objectID,
-			}).WithError(err).Error("writing response")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+// writeReadError maps errors returned by object reads onto response codes.
+func writeReadError(w http.ResponseWriter, objectID string, err error, msg string) {
+	switch {
+	case err == core.ErrNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, core.ErrInvalidRange):
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+	case errors.Is(err, core.ErrQuorumNotReached), errors.Is(err, core.ErrPreconditionFailed):
+		// Pinned reads fail with a failed precondition when the object was overwritten meanwhile.
+		logrus.WithFields(logrus.Fields{
+			"id": objectID,
+		}).WithError(err).Error(msg)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		logrus.WithFields(logrus.Fields{
+			"id": objectID,
+		}).WithError(err).Error(msg)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func writeObjectInfoHeaders(w http.ResponseWriter, info core.ObjectInfo) {
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	writeMetadataHeaders(w, info.Metadata)
+	writeValidatorHeaders(w, info)
+}
+
+// writeNotModified answers a conditional read with the validators only, as a 304 has no body.
+func writeNotModified(w http.ResponseWriter, info core.ObjectInfo) {
+	writeValidatorHeaders(w, info)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+func writeValidatorHeaders(w http.ResponseWriter, info core.ObjectInfo) {
+	if info.ETag != "" {
+		w.Header().Set("ETag", strconv.Quote(info.ETag))
+	}
+	if !info.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
 	}
 }
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/spacelift-io/homework-object-storage/internal/tracing"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRouterTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	objectDistributor := distributor.NewObjectDistributor(util.NewConsistentHashStorageSelector(), distributor.WithReplication(2, 2))
+	for _, storageID := range []string{"a", "b", "c"} {
+		objectDistributor.AddStorage(storageID, memory.NewObjectStorage(), 1)
+	}
+	router := Router(objectDistributor)
+
+	r := httptest.NewRequest(http.MethodPut, "/object/traced", strings.NewReader("blob"))
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	putSpan, requestSpan := spans[0], spans[1]
+
+	t.Run("request span continues client trace", func(t *testing.T) {
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", requestSpan.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", requestSpan.Parent().SpanID().String())
+		assert.Contains(t, requestSpan.Attributes(), tracing.ObjectID("traced"))
+	})
+
+	t.Run("distributor span records selected storages", func(t *testing.T) {
+		assert.Equal(t, "distributor.put", putSpan.Name())
+		assert.Equal(t, requestSpan.SpanContext().SpanID(), putSpan.Parent().SpanID())
+		assert.Contains(t, putSpan.Attributes(), tracing.ObjectID("traced"))
+
+		var storageIDs []string
+		for _, attr := range putSpan.Attributes() {
+			if attr.Key == tracing.StorageIDs(nil).Key {
+				storageIDs = attr.Value.AsStringSlice()
+			}
+		}
+		assert.Len(t, storageIDs, 2)
+	})
+}
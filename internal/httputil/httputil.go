@@ -0,0 +1,65 @@
+// Package httputil holds helpers shared by the HTTP APIs.
+package httputil
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+)
+
+// MaxUserMetadataSize matches the limit S3 compatible storages put on user metadata.
+const MaxUserMetadataSize = 2 << 10
+
+// ErrMetadataTooLarge is returned by ParseMetadata when user metadata exceeds MaxUserMetadataSize.
+var ErrMetadataTooLarge = errors.New("user metadata too large")
+
+// ParseMetadata collects the object metadata from the headers, user metadata from those starting with userMetadataPrefix.
+func ParseMetadata(header http.Header, userMetadataPrefix string) (core.ObjectMetadata, error) {
+	metadata := core.ObjectMetadata{
+		ContentType:        header.Get("Content-Type"),
+		ContentEncoding:    header.Get("Content-Encoding"),
+		ContentDisposition: header.Get("Content-Disposition"),
+	}
+
+	size := 0
+	for key, values := range header {
+		// Header keys are canonical already, so the remaining name is canonical as well.
+		name := strings.TrimPrefix(key, userMetadataPrefix)
+		if name == key || name == "" || len(values) == 0 {
+			continue
+		}
+		if metadata.UserMetadata == nil {
+			metadata.UserMetadata = make(map[string]string)
+		}
+		metadata.UserMetadata[name] = strings.Join(values, ",")
+
+		size += len(name) + len(metadata.UserMetadata[name])
+		if size > MaxUserMetadataSize {
+			return core.ObjectMetadata{}, ErrMetadataTooLarge
+		}
+	}
+	return metadata, nil
+}
+
+// WriteJSON writes v as a JSON response with the status, encoding failures can only be logged.
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.WithError(err).Error("writing response")
+	}
+}
+
+// CopyBody writes the object body after the headers are sent, so a failure can only be logged.
+func CopyBody(w io.Writer, body io.Reader, objectID string) {
+	if _, err := io.Copy(w, body); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"id": objectID,
+		}).WithError(err).Error("writing response")
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentHandler(t *testing.T) {
+	handler := InstrumentHandler("test", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if len(body) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	t.Run("request is counted with its status code and body sizes", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/", strings.NewReader("hello")))
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("test", http.MethodPut, "200")))
+		assert.Equal(t, 5.0, testutil.ToFloat64(httpReceivedBytes.WithLabelValues("test", http.MethodPut)))
+		assert.Equal(t, 2.0, testutil.ToFloat64(httpSentBytes.WithLabelValues("test", http.MethodPut)))
+	})
+
+	t.Run("when status is written explicitly, should be used as code", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/", nil))
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("test", http.MethodPut, "400")))
+	})
+
+	t.Run("when method is unknown, should be grouped as other", func(t *testing.T) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PROPFIND", "/", strings.NewReader("x")))
+
+		assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("test", "OTHER", "200")))
+	})
+}
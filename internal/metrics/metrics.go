@@ -0,0 +1,121 @@
+// Package metrics defines the Prometheus metrics of the object storage, they are served by Handler.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+)
+
+const namespace = "objectstorage"
+
+// Results label finished operations, expected outcomes are told apart from failures.
+const (
+	resultOK                 = "ok"
+	resultNotFound           = "not_found"
+	resultPreconditionFailed = "precondition_failed"
+	resultError              = "error"
+)
+
+var (
+	distributorOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "distributor",
+		Name:      "operation_duration_seconds",
+		Help:      "Duration of object distributor operations, reads are measured until the body is available.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "result"})
+
+	distributorRepairs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "distributor",
+		Name:      "repairs_total",
+		Help:      "Stale replicas copied over by read repair.",
+	}, []string{"result"})
+
+	storageRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of requests to storage nodes.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"address", "operation"})
+
+	storageRequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "request_errors_total",
+		Help:      "Failed requests to storage nodes, missing objects aren't counted as failures.",
+	}, []string{"address", "operation"})
+
+	ringMembers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ring",
+		Name:      "members",
+		Help:      "Storage nodes currently in the ring.",
+	})
+
+	storageHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "healthy",
+		Help:      "Whether the storage node passed its last health check, nodes failing it are removed from the ring.",
+	}, []string{"storage"})
+)
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ObserveDistributorOperation records an operation started at start which returned err.
+func ObserveDistributorOperation(operation string, start time.Time, err error) {
+	distributorOperationDuration.WithLabelValues(operation, result(err)).Observe(time.Since(start).Seconds())
+}
+
+func ObserveRepair(err error) {
+	distributorRepairs.WithLabelValues(result(err)).Inc()
+}
+
+// ObserveStorageRequest records a request to the storage node at address started at start which returned err.
+func ObserveStorageRequest(address, operation string, start time.Time, err error) {
+	storageRequestDuration.WithLabelValues(address, operation).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, core.ErrNotFound) {
+		storageRequestErrors.WithLabelValues(address, operation).Inc()
+	}
+}
+
+func SetRingMembers(n int) {
+	ringMembers.Set(float64(n))
+}
+
+func SetStorageHealthy(storageID string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	storageHealthy.WithLabelValues(storageID).Set(value)
+}
+
+// ForgetStorage drops the series of a storage node which is gone for good.
+func ForgetStorage(storageID string) {
+	storageHealthy.DeleteLabelValues(storageID)
+}
+
+func result(err error) string {
+	switch {
+	case err == nil:
+		return resultOK
+	case errors.Is(err, core.ErrNotFound):
+		return resultNotFound
+	case errors.Is(err, core.ErrPreconditionFailed):
+		return resultPreconditionFailed
+	default:
+		return resultError
+	}
+}
@@ -0,0 +1,34 @@
+package rpc
+
+import (
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	objectstoragev1 "github.com/spacelift-io/homework-object-storage/internal/gen/objectstorage/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toProtoObjectInfo(info core.ObjectInfo) *objectstoragev1.ObjectInfo {
+	object := &objectstoragev1.ObjectInfo{
+		Id:   info.ID,
+		Size: info.Size,
+		Etag: info.ETag,
+		Metadata: &objectstoragev1.ObjectMetadata{
+			ContentType:        info.Metadata.ContentType,
+			ContentEncoding:    info.Metadata.ContentEncoding,
+			ContentDisposition: info.Metadata.ContentDisposition,
+			UserMetadata:       info.Metadata.UserMetadata,
+		},
+	}
+	if !info.LastModified.IsZero() {
+		object.LastModified = timestamppb.New(info.LastModified)
+	}
+	return object
+}
+
+func fromProtoMetadata(metadata *objectstoragev1.ObjectMetadata) core.ObjectMetadata {
+	return core.ObjectMetadata{
+		ContentType:        metadata.GetContentType(),
+		ContentEncoding:    metadata.GetContentEncoding(),
+		ContentDisposition: metadata.GetContentDisposition(),
+		UserMetadata:       metadata.GetUserMetadata(),
+	}
+}
@@ -0,0 +1,33 @@
+package rpc
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// recoverUnary and recoverStream turn panics into Internal errors, like the recovery handler of the HTTP server.
+func recoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer recoverPanic(info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+func recoverStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverPanic(info.FullMethod, &err)
+	return handler(srv, stream)
+}
+
+func recoverPanic(method string, err *error) {
+	if p := recover(); p != nil {
+		logrus.WithFields(logrus.Fields{
+			"method": method,
+			"panic":  p,
+			"stack":  string(debug.Stack()),
+		}).Error("recovered from panic")
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
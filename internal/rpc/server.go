@@ -0,0 +1,229 @@
+// Package rpc serves the object storage over gRPC, see proto/objectstorage/v1 for the API.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	objectstoragev1 "github.com/spacelift-io/homework-object-storage/internal/gen/objectstorage/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// chunkSize bounds the data sent in a single Get message, well below the default 4MiB message limit.
+	chunkSize = 64 * 1024
+
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+type server struct {
+	objectstoragev1.UnimplementedObjectStorageServiceServer
+
+	objectDistributor *distributor.ObjectDistributor
+}
+
+// NewServer returns a gRPC server with the object storage service registered.
+func NewServer(objectDistributor *distributor.ObjectDistributor, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(recoverUnary),
+		grpc.ChainStreamInterceptor(recoverStream),
+	}, opts...)
+
+	grpcServer := grpc.NewServer(opts...)
+	objectstoragev1.RegisterObjectStorageServiceServer(grpcServer, &server{objectDistributor: objectDistributor})
+	return grpcServer
+}
+
+func (s *server) Put(stream objectstoragev1.ObjectStorageService_PutServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	header := req.GetHeader()
+	if header == nil {
+		return status.Error(codes.InvalidArgument, "first message has to be the header")
+	}
+	if err := validateObjectID(header.GetId()); err != nil {
+		return err
+	}
+
+	size := int64(-1)
+	if header.Size != nil {
+		if header.GetSize() < 0 {
+			return status.Error(codes.InvalidArgument, "size can't be negative")
+		}
+		size = header.GetSize()
+	}
+
+	body := &chunkReader{stream: stream, size: size}
+	info, err := s.objectDistributor.PutObject(stream.Context(), header.GetId(), body, size, distributor.PutOptions{
+		Metadata:    fromProtoMetadata(header.GetMetadata()),
+		IfMatch:     header.GetIfMatch(),
+		IfNoneMatch: header.GetIfNoneMatch(),
+	})
+	if body.err != nil {
+		// The stream broke or didn't match the declared size, whatever the storages made of it.
+		return body.err
+	}
+	if err != nil {
+		return toStatus(header.GetId(), err, "putting object")
+	}
+	return stream.SendAndClose(&objectstoragev1.PutResponse{Object: toProtoObjectInfo(info)})
+}
+
+func (s *server) Get(req *objectstoragev1.GetRequest, stream objectstoragev1.ObjectStorageService_GetServer) error {
+	if err := validateObjectID(req.GetId()); err != nil {
+		return err
+	}
+	opts, err := getOptions(req.GetConsistency())
+	if err != nil {
+		return err
+	}
+	if rng := req.GetRange(); rng != nil {
+		if rng.GetStart() < 0 || rng.GetEnd() < rng.GetStart() {
+			return status.Error(codes.InvalidArgument, "invalid range")
+		}
+		opts.Range = &core.ByteRange{Start: rng.GetStart(), End: rng.GetEnd()}
+	}
+
+	body, info, err := s.objectDistributor.GetObject(stream.Context(), req.GetId(), opts)
+	if err != nil {
+		return toStatus(req.GetId(), err, "getting object")
+	}
+	defer body.Close()
+
+	if err := stream.Send(&objectstoragev1.GetResponse{
+		Payload: &objectstoragev1.GetResponse_Info{Info: toProtoObjectInfo(info)},
+	}); err != nil {
+		return err
+	}
+
+	// Send blocks while the client's flow control window is full, so slow clients slow down the reads.
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&objectstoragev1.GetResponse{
+				Payload: &objectstoragev1.GetResponse_Chunk{Chunk: buf[:n]},
+			}); err != nil {
+				return err
+			}
+		}
+		switch err {
+		case nil:
+		// Ok
+		case io.EOF:
+			return nil
+		default:
+			return toStatus(req.GetId(), err, "reading object")
+		}
+	}
+}
+
+func (s *server) Head(ctx context.Context, req *objectstoragev1.HeadRequest) (*objectstoragev1.HeadResponse, error) {
+	if err := validateObjectID(req.GetId()); err != nil {
+		return nil, err
+	}
+	opts, err := getOptions(req.GetConsistency())
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.objectDistributor.StatObject(ctx, req.GetId(), opts)
+	if err != nil {
+		return nil, toStatus(req.GetId(), err, "getting object info")
+	}
+	return &objectstoragev1.HeadResponse{Object: toProtoObjectInfo(info)}, nil
+}
+
+func (s *server) Delete(ctx context.Context, req *objectstoragev1.DeleteRequest) (*objectstoragev1.DeleteResponse, error) {
+	if err := validateObjectID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	if err := s.objectDistributor.DeleteObject(ctx, req.GetId()); err != nil {
+		return nil, toStatus(req.GetId(), err, "deleting object")
+	}
+	return &objectstoragev1.DeleteResponse{}, nil
+}
+
+func (s *server) List(ctx context.Context, req *objectstoragev1.ListRequest) (*objectstoragev1.ListResponse, error) {
+	limit := int(req.GetLimit())
+	switch {
+	case limit == 0:
+		limit = defaultListLimit
+	case limit < 0 || limit > maxListLimit:
+		return nil, status.Errorf(codes.InvalidArgument, "limit has to be between 1 and %d", maxListLimit)
+	}
+
+	objects, truncated, err := s.objectDistributor.ListObjects(ctx, core.ListOptions{
+		Prefix:     req.GetPrefix(),
+		StartAfter: req.GetStartAfter(),
+		Limit:      limit,
+	})
+	if err != nil {
+		return nil, toStatus("", err, "listing objects")
+	}
+
+	response := &objectstoragev1.ListResponse{
+		Objects: make([]*objectstoragev1.ObjectInfo, 0, len(objects)),
+	}
+	for _, info := range objects {
+		response.Objects = append(response.Objects, toProtoObjectInfo(info))
+	}
+	if truncated {
+		response.NextStartAfter = objects[len(objects)-1].ID
+	}
+	return response, nil
+}
+
+func validateObjectID(objectID string) error {
+	if !core.ValidObjectID(objectID) {
+		return status.Error(codes.InvalidArgument, "id has to be 1 to 32 alphanumeric characters")
+	}
+	return nil
+}
+
+func getOptions(consistency objectstoragev1.Consistency) (distributor.GetOptions, error) {
+	switch consistency {
+	case objectstoragev1.Consistency_CONSISTENCY_UNSPECIFIED, objectstoragev1.Consistency_CONSISTENCY_ONE:
+		return distributor.GetOptions{Consistency: distributor.ConsistencyOne}, nil
+	case objectstoragev1.Consistency_CONSISTENCY_QUORUM:
+		return distributor.GetOptions{Consistency: distributor.ConsistencyQuorum}, nil
+	case objectstoragev1.Consistency_CONSISTENCY_ALL:
+		return distributor.GetOptions{Consistency: distributor.ConsistencyAll}, nil
+	default:
+		return distributor.GetOptions{}, status.Errorf(codes.InvalidArgument, "unknown consistency level %d", consistency)
+	}
+}
+
+// toStatus maps errors returned by the distributor onto status codes, logging the unexpected ones.
+func toStatus(objectID string, err error, msg string) error {
+	switch {
+	case err == core.ErrNotFound:
+		return status.Error(codes.NotFound, "object not found")
+	case err == core.ErrPreconditionFailed:
+		return status.Error(codes.FailedPrecondition, "precondition failed")
+	case errors.Is(err, core.ErrInvalidRange):
+		return status.Error(codes.OutOfRange, "range does not overlap the object")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.Is(err, core.ErrQuorumNotReached):
+		logrus.WithFields(logrus.Fields{
+			"id": objectID,
+		}).WithError(err).Error(msg)
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		logrus.WithFields(logrus.Fields{
+			"id": objectID,
+		}).WithError(err).Error(msg)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
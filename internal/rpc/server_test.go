@@ -0,0 +1,188 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	objectstoragev1 "github.com/spacelift-io/homework-object-storage/internal/gen/objectstorage/v1"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+func newTestClient(t *testing.T) objectstoragev1.ObjectStorageServiceClient {
+	objectDistributor := distributor.NewObjectDistributor(util.NewConsistentHashStorageSelector(), distributor.WithReplication(2, 2))
+	for _, storageID := range []string{"a", "b", "c"} {
+		objectDistributor.AddStorage(storageID, memory.NewObjectStorage(), 1)
+	}
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := NewServer(objectDistributor)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return objectstoragev1.NewObjectStorageServiceClient(conn)
+}
+
+func put(client objectstoragev1.ObjectStorageServiceClient, header *objectstoragev1.PutHeader, chunks ...[]byte) (*objectstoragev1.PutResponse, error) {
+	stream, err := client.Put(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&objectstoragev1.PutRequest{
+		Payload: &objectstoragev1.PutRequest_Header{Header: header},
+	}); err != nil {
+		return nil, err
+	}
+	for _, chunk := range chunks {
+		if err := stream.Send(&objectstoragev1.PutRequest{
+			Payload: &objectstoragev1.PutRequest_Chunk{Chunk: chunk},
+		}); err != nil {
+			break
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+func get(client objectstoragev1.ObjectStorageServiceClient, req *objectstoragev1.GetRequest) (*objectstoragev1.ObjectInfo, []byte, error) {
+	stream, err := client.Get(context.Background(), req)
+	if err != nil {
+		return nil, nil, err
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var blob bytes.Buffer
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return first.GetInfo(), blob.Bytes(), nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		blob.Write(resp.GetChunk())
+	}
+}
+
+func TestServer(t *testing.T) {
+	client := newTestClient(t)
+	blob := bytes.Repeat([]byte("0123456789"), 10000)
+
+	t.Run("object is streamed in and out with its metadata", func(t *testing.T) {
+		resp, err := put(client, &objectstoragev1.PutHeader{
+			Id:       "large",
+			Size:     proto.Int64(int64(len(blob))),
+			Metadata: &objectstoragev1.ObjectMetadata{ContentType: "text/plain"},
+		}, blob[:30000], blob[30000:])
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(blob)), resp.GetObject().GetSize())
+		assert.NotEmpty(t, resp.GetObject().GetEtag())
+
+		info, body, err := get(client, &objectstoragev1.GetRequest{Id: "large"})
+		require.NoError(t, err)
+		assert.Equal(t, blob, body)
+		assert.Equal(t, "text/plain", info.GetMetadata().GetContentType())
+	})
+
+	t.Run("object of unknown size is stored", func(t *testing.T) {
+		_, err := put(client, &objectstoragev1.PutHeader{Id: "unsized"}, []byte("hello "), []byte("world"))
+		require.NoError(t, err)
+
+		head, err := client.Head(context.Background(), &objectstoragev1.HeadRequest{Id: "unsized"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(11), head.GetObject().GetSize())
+	})
+
+	t.Run("object range is returned", func(t *testing.T) {
+		info, body, err := get(client, &objectstoragev1.GetRequest{
+			Id:    "unsized",
+			Range: &objectstoragev1.ByteRange{Start: 6, End: 10},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "world", string(body))
+		assert.Equal(t, int64(11), info.GetSize())
+	})
+
+	t.Run("objects are listed in pages", func(t *testing.T) {
+		resp, err := client.List(context.Background(), &objectstoragev1.ListRequest{Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, resp.GetObjects(), 1)
+		assert.Equal(t, "large", resp.GetObjects()[0].GetId())
+		assert.Equal(t, "large", resp.GetNextStartAfter())
+
+		resp, err = client.List(context.Background(), &objectstoragev1.ListRequest{StartAfter: resp.GetNextStartAfter()})
+		require.NoError(t, err)
+		require.Len(t, resp.GetObjects(), 1)
+		assert.Equal(t, "unsized", resp.GetObjects()[0].GetId())
+		assert.Empty(t, resp.GetNextStartAfter())
+	})
+
+	t.Run("object is deleted", func(t *testing.T) {
+		_, err := client.Delete(context.Background(), &objectstoragev1.DeleteRequest{Id: "unsized"})
+		require.NoError(t, err)
+
+		_, err = client.Head(context.Background(), &objectstoragev1.HeadRequest{Id: "unsized"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("when stream is shorter than declared size, should return invalid argument", func(t *testing.T) {
+		_, err := put(client, &objectstoragev1.PutHeader{Id: "short", Size: proto.Int64(10)}, []byte("abc"))
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.Head(context.Background(), &objectstoragev1.HeadRequest{Id: "short"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("when stream is longer than declared size, should return invalid argument", func(t *testing.T) {
+		_, err := put(client, &objectstoragev1.PutHeader{Id: "long", Size: proto.Int64(2)}, []byte("abc"))
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("when header is missing, should return invalid argument", func(t *testing.T) {
+		stream, err := client.Put(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&objectstoragev1.PutRequest{
+			Payload: &objectstoragev1.PutRequest_Chunk{Chunk: []byte("abc")},
+		}))
+		_, err = stream.CloseAndRecv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("when precondition fails, should return failed precondition", func(t *testing.T) {
+		_, err := put(client, &objectstoragev1.PutHeader{Id: "large", IfNoneMatch: []string{"*"}}, []byte("abc"))
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("when id is invalid, should return invalid argument", func(t *testing.T) {
+		_, err := client.Head(context.Background(), &objectstoragev1.HeadRequest{Id: "not/valid"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("when object doesn't exist, should return not found", func(t *testing.T) {
+		_, _, err := get(client, &objectstoragev1.GetRequest{Id: "missing"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
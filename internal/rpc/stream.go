@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"io"
+
+	objectstoragev1 "github.com/spacelift-io/homework-object-storage/internal/gen/objectstorage/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// chunkReader reads the data chunks of a Put stream. Messages are only received when storages ask
+// for more data, so gRPC flow control pushes back on clients sending faster than storages write.
+type chunkReader struct {
+	stream objectstoragev1.ObjectStorageService_PutServer
+	// size is the declared object size, -1 when it's unknown.
+	size int64
+	read int64
+	buf  []byte
+	// err is set when the stream failed or didn't match the declared size.
+	err error
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	for len(c.buf) == 0 {
+		req, err := c.stream.Recv()
+		if err == io.EOF {
+			if c.size >= 0 && c.read < c.size {
+				c.err = status.Errorf(codes.InvalidArgument, "stream ended after %d of %d bytes", c.read, c.size)
+				return 0, c.err
+			}
+			return 0, io.EOF
+		}
+		if err != nil {
+			c.err = err
+			return 0, err
+		}
+		if req.GetHeader() != nil {
+			c.err = status.Error(codes.InvalidArgument, "header can only be sent once")
+			return 0, c.err
+		}
+
+		c.buf = req.GetChunk()
+		c.read += int64(len(c.buf))
+		if c.size >= 0 && c.read > c.size {
+			c.err = status.Errorf(codes.InvalidArgument, "stream is longer than %d bytes", c.size)
+			return 0, c.err
+		}
+	}
+
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
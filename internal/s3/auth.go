@@ -0,0 +1,376 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signV4Algorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat   = "20060102T150405Z"
+	scopeDateFormat = "20060102"
+	serviceName     = "s3"
+	scopeTerminator = "aws4_request"
+
+	maxClockSkew     = 15 * time.Minute
+	maxPresignExpiry = 7 * 24 * time.Hour
+
+	unsignedPayload                 = "UNSIGNED-PAYLOAD"
+	streamingPayload                = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	streamingPayloadTrailer         = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	streamingUnsignedPayloadTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+)
+
+// signature is a parsed SigV4 signature, either from the Authorization header or a presigned URL.
+type signature struct {
+	accessKey     string
+	scopeDate     string
+	region        string
+	signedHeaders []string
+	signature     string
+	date          time.Time
+	payloadHash   string
+}
+
+func (s signature) scope() string {
+	return strings.Join([]string{s.scopeDate, s.region, serviceName, scopeTerminator}, "/")
+}
+
+// authenticator verifies SigV4 signed requests against static credentials.
+type authenticator struct {
+	// credentials maps access keys onto their secret keys.
+	credentials map[string]string
+	now         func() time.Time
+}
+
+// authenticate verifies the request signature. Request bodies are replaced by readers which
+// verify the payload while it's read, so payload errors surface from reading the body.
+func (a *authenticator) authenticate(r *http.Request) error {
+	var sig signature
+	var err error
+	if r.URL.Query().Get("X-Amz-Algorithm") != "" {
+		sig, err = a.parsePresigned(r)
+	} else {
+		sig, err = a.parseAuthorization(r)
+	}
+	if err != nil {
+		return err
+	}
+
+	secretKey, ok := a.credentials[sig.accessKey]
+	if !ok {
+		return errInvalidAccessKeyID
+	}
+	signingKey := deriveSigningKey(secretKey, sig.scopeDate, sig.region)
+
+	stringToSign := strings.Join([]string{
+		signV4Algorithm,
+		sig.date.Format(amzDateFormat),
+		sig.scope(),
+		hexSHA256([]byte(canonicalRequest(r, sig.signedHeaders, sig.payloadHash))),
+	}, "\n")
+	expected := hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
+	if !hmac.Equal([]byte(expected), []byte(sig.signature)) {
+		return errSignatureDoesNotMatch
+	}
+
+	return a.verifyPayload(r, sig, signingKey)
+}
+
+func (a *authenticator) parseAuthorization(r *http.Request) (signature, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return signature{}, errAccessDenied
+	}
+	algorithm, fields, ok := strings.Cut(header, " ")
+	if !ok || algorithm != signV4Algorithm {
+		return signature{}, errAuthorizationMalformed
+	}
+
+	var sig signature
+	for _, field := range strings.Split(fields, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			return signature{}, errAuthorizationMalformed
+		}
+		switch key {
+		case "Credential":
+			if err := sig.parseCredential(value); err != nil {
+				return signature{}, err
+			}
+		case "SignedHeaders":
+			sig.signedHeaders = strings.Split(value, ";")
+		case "Signature":
+			sig.signature = value
+		}
+	}
+	if sig.accessKey == "" || len(sig.signedHeaders) == 0 || sig.signature == "" {
+		return signature{}, errAuthorizationMalformed
+	}
+
+	date, err := parseRequestDate(r)
+	if err != nil {
+		return signature{}, err
+	}
+	if date.Format(scopeDateFormat) != sig.scopeDate {
+		return signature{}, errAuthorizationMalformed
+	}
+	if skew := a.now().Sub(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return signature{}, errRequestTimeTooSkewed
+	}
+	sig.date = date
+
+	sig.payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+	if sig.payloadHash == "" {
+		return signature{}, errMissingContentSHA256
+	}
+	return sig, nil
+}
+
+func (a *authenticator) parsePresigned(r *http.Request) (signature, error) {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != signV4Algorithm {
+		return signature{}, errAuthorizationMalformed
+	}
+
+	var sig signature
+	if err := sig.parseCredential(query.Get("X-Amz-Credential")); err != nil {
+		return signature{}, err
+	}
+	sig.signedHeaders = strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	sig.signature = query.Get("X-Amz-Signature")
+	if sig.signature == "" {
+		return signature{}, errAuthorizationMalformed
+	}
+
+	date, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date"))
+	if err != nil || date.Format(scopeDateFormat) != sig.scopeDate {
+		return signature{}, errAuthorizationMalformed
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 || time.Duration(expires)*time.Second > maxPresignExpiry {
+		return signature{}, errAuthorizationMalformed
+	}
+	now := a.now()
+	if date.Sub(now) > maxClockSkew {
+		return signature{}, errRequestTimeTooSkewed
+	}
+	if now.After(date.Add(time.Duration(expires) * time.Second)) {
+		return signature{}, errExpiredRequest
+	}
+	sig.date = date
+
+	// Presigned URLs are shared before the payload is known.
+	sig.payloadHash = query.Get("X-Amz-Content-Sha256")
+	if sig.payloadHash == "" {
+		sig.payloadHash = unsignedPayload
+	}
+	return sig, nil
+}
+
+// parseCredential parses "<access key>/<date>/<region>/s3/aws4_request".
+func (s *signature) parseCredential(credential string) error {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[3] != serviceName || parts[4] != scopeTerminator {
+		return errAuthorizationMalformed
+	}
+	s.accessKey, s.scopeDate, s.region = parts[0], parts[1], parts[2]
+	return nil
+}
+
+func parseRequestDate(r *http.Request) (time.Time, error) {
+	if value := r.Header.Get("X-Amz-Date"); value != "" {
+		date, err := time.Parse(amzDateFormat, value)
+		if err != nil {
+			return time.Time{}, errAuthorizationMalformed
+		}
+		return date, nil
+	}
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}, errAccessDenied
+	}
+	return date, nil
+}
+
+// verifyPayload wraps the body in a reader checking it against the signed payload hash and Content-MD5.
+func (a *authenticator) verifyPayload(r *http.Request, sig signature, signingKey []byte) error {
+	switch sig.payloadHash {
+	case unsignedPayload:
+	case streamingPayload, streamingPayloadTrailer, streamingUnsignedPayloadTrailer:
+		decodedLength, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil || decodedLength < 0 {
+			return errMissingContentLength
+		}
+		r.Body = newChunkedReader(r.Body, chunkedOptions{
+			signed:        sig.payloadHash != streamingUnsignedPayloadTrailer,
+			trailer:       sig.payloadHash != streamingPayload,
+			signingKey:    signingKey,
+			date:          sig.date.Format(amzDateFormat),
+			scope:         sig.scope(),
+			seedSignature: sig.signature,
+			decodedLength: decodedLength,
+		})
+		r.ContentLength = decodedLength
+	default:
+		expected, err := hex.DecodeString(sig.payloadHash)
+		if err != nil || len(expected) != sha256.Size {
+			return errContentSHA256Mismatch
+		}
+		r.Body = newVerifyingReader(r.Body, r.ContentLength, sha256.New(), expected, errContentSHA256Mismatch)
+	}
+
+	if contentMD5 := r.Header.Get("Content-Md5"); contentMD5 != "" {
+		expected, err := base64.StdEncoding.DecodeString(contentMD5)
+		if err != nil || len(expected) != md5.Size {
+			return errInvalidDigest
+		}
+		r.Body = newVerifyingReader(r.Body, r.ContentLength, md5.New(), expected, errBadDigest)
+	}
+	return nil
+}
+
+func canonicalRequest(r *http.Request, signedHeaders []string, payloadHash string) string {
+	headers := make([]string, 0, len(signedHeaders))
+	for _, name := range signedHeaders {
+		headers = append(headers, name+":"+canonicalHeaderValue(r, name)+"\n")
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		encodePath(r.URL.Path),
+		canonicalQuery(r.URL.Query()),
+		strings.Join(headers, ""),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+func canonicalHeaderValue(r *http.Request, name string) string {
+	var values []string
+	switch name {
+	case "host":
+		values = []string{r.Host}
+	case "content-length":
+		values = r.Header.Values(name)
+		if len(values) == 0 && r.ContentLength >= 0 {
+			values = []string{strconv.FormatInt(r.ContentLength, 10)}
+		}
+	case "transfer-encoding":
+		// net/http moves it out of the headers.
+		values = r.TransferEncoding
+	default:
+		values = r.Header.Values(name)
+	}
+
+	trimmed := make([]string, 0, len(values))
+	for _, value := range values {
+		trimmed = append(trimmed, strings.Join(strings.Fields(value), " "))
+	}
+	return strings.Join(trimmed, ",")
+}
+
+// canonicalQuery sorts and encodes the query, the signature of presigned URLs isn't part of it.
+func canonicalQuery(query url.Values) string {
+	params := make([]string, 0, len(query))
+	for key, values := range query {
+		if key == "X-Amz-Signature" {
+			continue
+		}
+		for _, value := range values {
+			params = append(params, encodeURIComponent(key)+"="+encodeURIComponent(value))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// encodePath encodes every path segment the way SigV4 signs S3 paths, slashes are kept.
+func encodePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = encodeURIComponent(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// encodeURIComponent percent encodes everything but the unreserved characters of RFC 3986.
+func encodeURIComponent(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+	return b.String()
+}
+
+func deriveSigningKey(secretKey, scopeDate, region string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), []byte(scopeDate))
+	key = hmacSHA256(key, []byte(region))
+	key = hmacSHA256(key, []byte(serviceName))
+	return hmacSHA256(key, []byte(scopeTerminator))
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// verifyingReader hashes the body and fails at its end unless the hash matches. Storages stop
+// reading once they got as many bytes as announced and may ignore an error returned along with
+// the last bytes, so a mismatch is returned instead of them and the body never looks complete.
+type verifyingReader struct {
+	r        io.ReadCloser
+	size     int64
+	read     int64
+	h        hash.Hash
+	expected []byte
+	mismatch error
+	err      error
+}
+
+func newVerifyingReader(r io.ReadCloser, size int64, h hash.Hash, expected []byte, mismatch error) *verifyingReader {
+	return &verifyingReader{r: r, size: size, h: h, expected: expected, mismatch: mismatch}
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+	v.read += int64(n)
+	if err == io.EOF || (v.size >= 0 && v.read >= v.size) {
+		if !hmac.Equal(v.h.Sum(nil), v.expected) {
+			v.err = v.mismatch
+			return 0, v.err
+		}
+	}
+	return n, err
+}
+
+func (v *verifyingReader) Close() error {
+	return v.r.Close()
+}
@@ -0,0 +1,220 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxChunkSize bounds the memory a single aws-chunked chunk takes, as it's verified before being passed on.
+const maxChunkSize = 16 << 20
+
+const (
+	chunkSignaturePrefix   = ";chunk-signature="
+	trailerSignatureHeader = "x-amz-trailer-signature"
+	chunkAlgorithm         = "AWS4-HMAC-SHA256-PAYLOAD"
+	trailerAlgorithm       = "AWS4-HMAC-SHA256-TRAILER"
+)
+
+var emptySHA256 = hexSHA256(nil)
+
+type chunkedOptions struct {
+	// signed chunks carry signatures chained to the request signature.
+	signed bool
+	// trailer payloads end with trailing headers after the last chunk.
+	trailer       bool
+	signingKey    []byte
+	date          string
+	scope         string
+	seedSignature string
+	decodedLength int64
+}
+
+// chunkedReader decodes an aws-chunked payload. Every chunk is verified before any of its bytes are returned.
+// Checksums sent in trailers are not verified, the signature of the trailer itself is.
+type chunkedReader struct {
+	r             *bufio.Reader
+	closer        io.Closer
+	opts          chunkedOptions
+	prevSignature string
+	chunk         []byte
+	read          int64
+	done          bool
+	err           error
+}
+
+func newChunkedReader(r io.ReadCloser, opts chunkedOptions) *chunkedReader {
+	return &chunkedReader{
+		r:             bufio.NewReader(r),
+		closer:        r,
+		opts:          opts,
+		prevSignature: opts.seedSignature,
+	}
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	for len(c.chunk) == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		if c.err = c.readChunk(); c.err != nil {
+			return 0, c.err
+		}
+		// Storages stop reading at the decoded length, so the end of the payload is verified
+		// before the last chunk is passed on.
+		if len(c.chunk) > 0 && c.read+int64(len(c.chunk)) == c.opts.decodedLength {
+			if c.err = c.readEnd(); c.err != nil {
+				c.chunk = nil
+				return 0, c.err
+			}
+		}
+	}
+
+	n := copy(p, c.chunk)
+	c.chunk = c.chunk[n:]
+	c.read += int64(n)
+	return n, nil
+}
+
+// readEnd reads the final empty chunk and the trailer following the current chunk.
+func (c *chunkedReader) readEnd() error {
+	data, read := c.chunk, c.read
+	c.chunk, c.read = nil, c.read+int64(len(data))
+	if err := c.readChunk(); err != nil {
+		return err
+	}
+	if len(c.chunk) > 0 || !c.done {
+		return errIncompleteBody
+	}
+	c.chunk, c.read = data, read
+	return nil
+}
+
+func (c *chunkedReader) Close() error {
+	return c.closer.Close()
+}
+
+func (c *chunkedReader) readChunk() error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+
+	sizeHex, chunkSignature := line, ""
+	if i := strings.Index(line, ";"); i >= 0 {
+		sizeHex = line[:i]
+		if !strings.HasPrefix(line[i:], chunkSignaturePrefix) {
+			return errMalformedChunk
+		}
+		chunkSignature = line[i+len(chunkSignaturePrefix):]
+	}
+	size, err := strconv.ParseInt(sizeHex, 16, 64)
+	if err != nil || size < 0 || size > maxChunkSize {
+		return errMalformedChunk
+	}
+	if c.read+size > c.opts.decodedLength {
+		return errIncompleteBody
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return errIncompleteBody
+	}
+	// With trailers the last chunk is followed by the trailing headers instead of an empty line.
+	if size > 0 || !c.opts.trailer {
+		if err := c.expectLine(""); err != nil {
+			return err
+		}
+	}
+
+	if c.opts.signed {
+		expected := c.sign(chunkAlgorithm, emptySHA256, hexSHA256(data))
+		if !hmac.Equal([]byte(expected), []byte(chunkSignature)) {
+			return errSignatureDoesNotMatch
+		}
+		c.prevSignature = expected
+	}
+
+	if size == 0 {
+		if c.read != c.opts.decodedLength {
+			return errIncompleteBody
+		}
+		c.done = true
+		if c.opts.trailer {
+			return c.readTrailer()
+		}
+		return nil
+	}
+	c.chunk = data
+	return nil
+}
+
+// readTrailer reads the trailing headers up to the closing empty line and verifies their signature.
+func (c *chunkedReader) readTrailer() error {
+	var trailer bytes.Buffer
+	trailerSignature := ""
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return errMalformedChunk
+		}
+		if strings.ToLower(name) == trailerSignatureHeader {
+			trailerSignature = strings.TrimSpace(value)
+			continue
+		}
+		trailer.WriteString(strings.ToLower(name) + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	if c.opts.signed {
+		expected := c.sign(trailerAlgorithm, hexSHA256(trailer.Bytes()))
+		if !hmac.Equal([]byte(expected), []byte(trailerSignature)) {
+			return errSignatureDoesNotMatch
+		}
+	}
+	return nil
+}
+
+func (c *chunkedReader) sign(algorithm string, hashes ...string) string {
+	stringToSign := strings.Join(append([]string{algorithm, c.opts.date, c.opts.scope, c.prevSignature}, hashes...), "\n")
+	return hex.EncodeToString(hmacSHA256(c.opts.signingKey, []byte(stringToSign)))
+}
+
+func (c *chunkedReader) readLine() (string, error) {
+	line, err := c.r.ReadSlice('\n')
+	if err != nil {
+		if err == bufio.ErrBufferFull {
+			return "", errMalformedChunk
+		}
+		return "", errIncompleteBody
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return "", errMalformedChunk
+	}
+	return string(line[:len(line)-2]), nil
+}
+
+func (c *chunkedReader) expectLine(expected string) error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if line != expected {
+		return errMalformedChunk
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+)
+
+// apiError is an error reported to clients in the S3 error format.
+type apiError struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+func (e *apiError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var (
+	errAccessDenied            = &apiError{"AccessDenied", "Access Denied.", http.StatusForbidden}
+	errAuthorizationMalformed  = &apiError{"AuthorizationHeaderMalformed", "The authorization header is malformed.", http.StatusBadRequest}
+	errInvalidAccessKeyID      = &apiError{"InvalidAccessKeyId", "The access key ID you provided does not exist in our records.", http.StatusForbidden}
+	errSignatureDoesNotMatch   = &apiError{"SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden}
+	errRequestTimeTooSkewed    = &apiError{"RequestTimeTooSkewed", "The difference between the request time and the server's time is too large.", http.StatusForbidden}
+	errExpiredRequest          = &apiError{"AccessDenied", "Request has expired.", http.StatusForbidden}
+	errMissingContentSHA256    = &apiError{"InvalidRequest", "Missing required header for this request: x-amz-content-sha256.", http.StatusBadRequest}
+	errContentSHA256Mismatch   = &apiError{"XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed.", http.StatusBadRequest}
+	errBadDigest               = &apiError{"BadDigest", "The Content-MD5 you specified did not match what we received.", http.StatusBadRequest}
+	errInvalidDigest           = &apiError{"InvalidDigest", "The Content-MD5 you specified is not valid.", http.StatusBadRequest}
+	errIncompleteBody          = &apiError{"IncompleteBody", "You did not provide the number of bytes specified by the Content-Length HTTP header.", http.StatusBadRequest}
+	errMalformedChunk          = &apiError{"InvalidRequest", "The aws-chunked payload is malformed.", http.StatusBadRequest}
+	errMissingContentLength    = &apiError{"MissingContentLength", "You must provide the Content-Length HTTP header.", http.StatusLengthRequired}
+	errMalformedXML            = &apiError{"MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest}
+	errInvalidArgument         = &apiError{"InvalidArgument", "Invalid argument.", http.StatusBadRequest}
+	errKeyTooLong              = &apiError{"KeyTooLongError", "Your key is too long.", http.StatusBadRequest}
+	errMetadataTooLarge        = &apiError{"MetadataTooLarge", "Your metadata headers exceed the maximum allowed metadata size.", http.StatusBadRequest}
+	errNoSuchBucket            = &apiError{"NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound}
+	errBucketAlreadyOwnedByYou = &apiError{"BucketAlreadyOwnedByYou", "The bucket you tried to create already exists, and you own it.", http.StatusConflict}
+	errNoSuchKey               = &apiError{"NoSuchKey", "The specified key does not exist.", http.StatusNotFound}
+	errNoSuchUpload            = &apiError{"NoSuchUpload", "The specified multipart upload does not exist.", http.StatusNotFound}
+	errInvalidPart             = &apiError{"InvalidPart", "One or more of the specified parts could not be found or are invalid.", http.StatusBadRequest}
+	errInvalidPartNumber       = &apiError{"InvalidArgument", "Part number must be an integer between 1 and 10000, inclusive.", http.StatusBadRequest}
+	errInvalidRange            = &apiError{"InvalidRange", "The requested range is not satisfiable.", http.StatusRequestedRangeNotSatisfiable}
+	errPreconditionFailed      = &apiError{"PreconditionFailed", "At least one of the preconditions you specified did not hold.", http.StatusPreconditionFailed}
+	errInvalidCopySource       = &apiError{"InvalidArgument", "Copy Source must mention the source bucket and key: sourcebucket/sourcekey.", http.StatusBadRequest}
+	errMethodNotAllowed        = &apiError{"MethodNotAllowed", "The specified method is not allowed against this resource.", http.StatusMethodNotAllowed}
+	errNotImplemented          = &apiError{"NotImplemented", "A header or query you provided implies functionality that is not implemented.", http.StatusNotImplemented}
+	errServiceUnavailable      = &apiError{"ServiceUnavailable", "Please reduce your request rate.", http.StatusServiceUnavailable}
+	errInternalError           = &apiError{"InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError}
+	errMultipartNotSupported   = &apiError{"NotImplemented", "Multipart uploads are not supported by the storage owning the key.", http.StatusNotImplemented}
+)
+
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource,omitempty"`
+}
+
+// toAPIError maps errors of the distributor and the storages onto S3 errors, notFound is used for core.ErrNotFound.
+func toAPIError(err error, notFound *apiError) *apiError {
+	var apiErr *apiError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr
+	case err == core.ErrNotFound:
+		return notFound
+	case errors.Is(err, core.ErrInvalidRange):
+		return errInvalidRange
+	case err == httputil.ErrMetadataTooLarge:
+		return errMetadataTooLarge
+	case errors.Is(err, core.ErrInvalidPart):
+		return errInvalidPart
+	case errors.Is(err, core.ErrPreconditionFailed):
+		return errPreconditionFailed
+	case errors.Is(err, core.ErrQuorumNotReached):
+		return errServiceUnavailable
+	case err == distributor.ErrMultipartNotSupported:
+		return errMultipartNotSupported
+	default:
+		return errInternalError
+	}
+}
+
+// writeError reports err to the client, errors which aren't the client's fault are logged.
+func writeError(w http.ResponseWriter, r *http.Request, err error, notFound *apiError, msg string) {
+	apiErr := toAPIError(err, notFound)
+	if apiErr.Status >= http.StatusInternalServerError {
+		logrus.WithFields(logrus.Fields{
+			"path": r.URL.Path,
+		}).WithError(err).Error(msg)
+	}
+
+	// Responses to HEAD requests carry no body, so only the status is left.
+	if r.Method == http.MethodHead {
+		w.WriteHeader(apiErr.Status)
+		return
+	}
+	writeXML(w, apiErr.Status, errorResponse{
+		Code:     apiErr.Code,
+		Message:  apiErr.Message,
+		Resource: r.URL.Path,
+	})
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return
+	}
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		logrus.WithError(err).Error("writing response")
+	}
+}
@@ -0,0 +1,277 @@
+package s3
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+)
+
+const maxListKeys = 1000
+
+// bucketCreationDate is reported for the single bucket, which isn't created at any particular time.
+var bucketCreationDate = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type listBucketsResult struct {
+	XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Owner   owner    `xml:"Owner"`
+	Buckets []bucket `xml:"Buckets>Bucket"`
+}
+
+type owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+type bucket struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+type locationConstraint struct {
+	XMLName xml.Name `xml:"LocationConstraint"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Region  string   `xml:",chardata"`
+}
+
+type listObjectsV2Result struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Xmlns                 string         `xml:"xmlns,attr"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int            `xml:"KeyCount"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	EncodingType          string         `xml:"EncodingType,omitempty"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Contents              []listedObject `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+type listObjectsV1Result struct {
+	XMLName        xml.Name       `xml:"ListBucketResult"`
+	Xmlns          string         `xml:"xmlns,attr"`
+	Name           string         `xml:"Name"`
+	Prefix         string         `xml:"Prefix"`
+	Delimiter      string         `xml:"Delimiter,omitempty"`
+	Marker         string         `xml:"Marker"`
+	NextMarker     string         `xml:"NextMarker,omitempty"`
+	MaxKeys        int            `xml:"MaxKeys"`
+	EncodingType   string         `xml:"EncodingType,omitempty"`
+	IsTruncated    bool           `xml:"IsTruncated"`
+	Contents       []listedObject `xml:"Contents"`
+	CommonPrefixes []commonPrefix `xml:"CommonPrefixes"`
+}
+
+type listedObject struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// listing is a page of keys and common prefixes, next continues the listing after it.
+type listing struct {
+	objects   []core.ObjectInfo
+	prefixes  []string
+	truncated bool
+	next      string
+}
+
+func (s *server) listBuckets(w http.ResponseWriter, r *http.Request) {
+	writeXML(w, http.StatusOK, listBucketsResult{
+		Xmlns: s3Namespace,
+		Buckets: []bucket{{
+			Name:         s.config.Bucket,
+			CreationDate: bucketCreationDate.Format(timestampFormat),
+		}},
+	})
+}
+
+func (s *server) getBucketLocation(w http.ResponseWriter, r *http.Request) {
+	writeXML(w, http.StatusOK, locationConstraint{Xmlns: s3Namespace, Region: s.config.Region})
+}
+
+func (s *server) listObjectsV2(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	maxKeys, err := parseMaxKeys(query.Get("max-keys"))
+	if err != nil {
+		writeError(w, r, err, nil, "")
+		return
+	}
+
+	startAfter := query.Get("start-after")
+	if token := query.Get("continuation-token"); token != "" {
+		decoded, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			writeError(w, r, errInvalidArgument, nil, "")
+			return
+		}
+		startAfter = string(decoded)
+	}
+
+	result, err := s.list(r.Context(), query.Get("prefix"), query.Get("delimiter"), startAfter, maxKeys)
+	if err != nil {
+		writeError(w, r, err, errNoSuchBucket, "listing objects")
+		return
+	}
+
+	encode := keyEncoder(query.Get("encoding-type"))
+	response := listObjectsV2Result{
+		Xmlns:             s3Namespace,
+		Name:              s.config.Bucket,
+		Prefix:            encode(query.Get("prefix")),
+		Delimiter:         encode(query.Get("delimiter")),
+		StartAfter:        encode(query.Get("start-after")),
+		ContinuationToken: query.Get("continuation-token"),
+		KeyCount:          len(result.objects) + len(result.prefixes),
+		MaxKeys:           maxKeys,
+		EncodingType:      query.Get("encoding-type"),
+		IsTruncated:       result.truncated,
+		Contents:          toListedObjects(result.objects, encode),
+		CommonPrefixes:    toCommonPrefixes(result.prefixes, encode),
+	}
+	if result.truncated {
+		response.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(result.next))
+	}
+	writeXML(w, http.StatusOK, response)
+}
+
+func (s *server) listObjectsV1(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	maxKeys, err := parseMaxKeys(query.Get("max-keys"))
+	if err != nil {
+		writeError(w, r, err, nil, "")
+		return
+	}
+
+	result, err := s.list(r.Context(), query.Get("prefix"), query.Get("delimiter"), query.Get("marker"), maxKeys)
+	if err != nil {
+		writeError(w, r, err, errNoSuchBucket, "listing objects")
+		return
+	}
+
+	encode := keyEncoder(query.Get("encoding-type"))
+	response := listObjectsV1Result{
+		Xmlns:          s3Namespace,
+		Name:           s.config.Bucket,
+		Prefix:         encode(query.Get("prefix")),
+		Delimiter:      encode(query.Get("delimiter")),
+		Marker:         encode(query.Get("marker")),
+		MaxKeys:        maxKeys,
+		EncodingType:   query.Get("encoding-type"),
+		IsTruncated:    result.truncated,
+		Contents:       toListedObjects(result.objects, encode),
+		CommonPrefixes: toCommonPrefixes(result.prefixes, encode),
+	}
+	if result.truncated {
+		response.NextMarker = encode(result.next)
+	}
+	writeXML(w, http.StatusOK, response)
+}
+
+// list pages through the distributor listing, rolling keys containing the delimiter after the prefix up into common prefixes.
+func (s *server) list(ctx context.Context, prefix, delimiter, startAfter string, maxKeys int) (listing, error) {
+	result := listing{}
+	if maxKeys == 0 {
+		return result, nil
+	}
+
+	for {
+		count := len(result.objects) + len(result.prefixes)
+		objects, truncated, err := s.objectDistributor.ListObjects(ctx, core.ListOptions{
+			Prefix:     prefix,
+			StartAfter: startAfter,
+			Limit:      maxKeys - count + 1,
+		})
+		if err != nil {
+			return listing{}, err
+		}
+
+		relisted := false
+		for _, info := range objects {
+			if len(result.objects)+len(result.prefixes) == maxKeys {
+				result.truncated = true
+				return result, nil
+			}
+
+			if delimiter != "" {
+				if i := strings.Index(info.ID[len(prefix):], delimiter); i >= 0 {
+					commonPrefix := info.ID[:len(prefix)+i+len(delimiter)]
+					result.prefixes = append(result.prefixes, commonPrefix)
+					// Keys sort bytewise, so no valid key under the common prefix sorts after this.
+					result.next = commonPrefix + string(utf8.MaxRune)
+					startAfter = result.next
+					relisted = true
+					break
+				}
+			}
+			result.objects = append(result.objects, info)
+			result.next = info.ID
+			startAfter = info.ID
+		}
+
+		if !relisted && !truncated {
+			return result, nil
+		}
+	}
+}
+
+func parseMaxKeys(value string) (int, error) {
+	if value == "" {
+		return maxListKeys, nil
+	}
+	maxKeys, err := strconv.Atoi(value)
+	if err != nil || maxKeys < 0 {
+		return 0, errInvalidArgument
+	}
+	if maxKeys > maxListKeys {
+		maxKeys = maxListKeys
+	}
+	return maxKeys, nil
+}
+
+// keyEncoder returns how keys are encoded in listings, clients ask for URL encoding to get keys XML can't carry.
+func keyEncoder(encodingType string) func(string) string {
+	if encodingType != "url" {
+		return func(key string) string { return key }
+	}
+	return encodePath
+}
+
+func toListedObjects(objects []core.ObjectInfo, encode func(string) string) []listedObject {
+	listed := make([]listedObject, 0, len(objects))
+	for _, info := range objects {
+		listed = append(listed, listedObject{
+			Key:          encode(info.ID),
+			LastModified: info.LastModified.UTC().Format(timestampFormat),
+			ETag:         strconv.Quote(info.ETag),
+			Size:         info.Size,
+			StorageClass: "STANDARD",
+		})
+	}
+	return listed
+}
+
+func toCommonPrefixes(prefixes []string, encode func(string) string) []commonPrefix {
+	listed := make([]commonPrefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		listed = append(listed, commonPrefix{Prefix: encode(prefix)})
+	}
+	return listed
+}
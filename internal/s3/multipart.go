@@ -0,0 +1,185 @@
+package s3
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+)
+
+const (
+	maxPartNumber = 10000
+	maxListParts  = 1000
+)
+
+type initiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type completeMultipartUpload struct {
+	Parts []struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+type completeMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+type listPartsResult struct {
+	XMLName              xml.Name     `xml:"ListPartsResult"`
+	Xmlns                string       `xml:"xmlns,attr"`
+	Bucket               string       `xml:"Bucket"`
+	Key                  string       `xml:"Key"`
+	UploadID             string       `xml:"UploadId"`
+	PartNumberMarker     int          `xml:"PartNumberMarker"`
+	NextPartNumberMarker int          `xml:"NextPartNumberMarker"`
+	MaxParts             int          `xml:"MaxParts"`
+	IsTruncated          bool         `xml:"IsTruncated"`
+	Parts                []listedPart `xml:"Part"`
+}
+
+type listedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+	Size       int64  `xml:"Size"`
+}
+
+func (s *server) createMultipartUpload(w http.ResponseWriter, r *http.Request, key string) {
+	metadata, err := httputil.ParseMetadata(r.Header, userMetadataPrefix)
+	if err != nil {
+		writeError(w, r, err, nil, "")
+		return
+	}
+
+	uploadID, err := s.objectDistributor.InitiateUpload(r.Context(), key, metadata)
+	if err != nil {
+		writeError(w, r, err, errNoSuchBucket, "initiating upload")
+		return
+	}
+
+	writeXML(w, http.StatusOK, initiateMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Bucket:   s.config.Bucket,
+		Key:      key,
+		UploadID: uploadID,
+	})
+}
+
+func (s *server) uploadPart(w http.ResponseWriter, r *http.Request, key string) {
+	query := r.URL.Query()
+	partNumber, err := strconv.Atoi(query.Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+		writeError(w, r, errInvalidPartNumber, nil, "")
+		return
+	}
+	if r.ContentLength < 0 {
+		writeError(w, r, errMissingContentLength, nil, "")
+		return
+	}
+
+	part, err := s.objectDistributor.PutUploadPart(r.Context(), key, query.Get("uploadId"), partNumber, r.Body, r.ContentLength)
+	if err != nil {
+		writeError(w, r, err, errNoSuchUpload, "putting upload part")
+		return
+	}
+
+	w.Header().Set("ETag", strconv.Quote(part.ETag))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, key string) {
+	var request completeMultipartUpload
+	if err := xml.NewDecoder(io.LimitReader(r.Body, maxXMLBodySize)).Decode(&request); err != nil || len(request.Parts) == 0 {
+		writeError(w, r, errMalformedXML, nil, "")
+		return
+	}
+	parts := make([]core.PartInfo, 0, len(request.Parts))
+	for _, part := range request.Parts {
+		parts = append(parts, core.PartInfo{PartNumber: part.PartNumber, ETag: strings.Trim(part.ETag, `"`)})
+	}
+
+	info, err := s.objectDistributor.CompleteUpload(r.Context(), key, r.URL.Query().Get("uploadId"), parts)
+	if err != nil {
+		writeError(w, r, err, errNoSuchUpload, "completing upload")
+		return
+	}
+
+	writeXML(w, http.StatusOK, completeMultipartUploadResult{
+		Xmlns:    s3Namespace,
+		Location: "/" + s.config.Bucket + "/" + encodePath(key),
+		Bucket:   s.config.Bucket,
+		Key:      key,
+		ETag:     strconv.Quote(info.ETag),
+	})
+}
+
+func (s *server) abortMultipartUpload(w http.ResponseWriter, r *http.Request, key string) {
+	if err := s.objectDistributor.AbortUpload(r.Context(), key, r.URL.Query().Get("uploadId")); err != nil {
+		writeError(w, r, err, errNoSuchUpload, "aborting upload")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) listParts(w http.ResponseWriter, r *http.Request, key string) {
+	query := r.URL.Query()
+	marker, _ := strconv.Atoi(query.Get("part-number-marker"))
+	maxParts := maxListParts
+	if value := query.Get("max-parts"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			writeError(w, r, errInvalidArgument, nil, "")
+			return
+		}
+		if parsed < maxParts {
+			maxParts = parsed
+		}
+	}
+
+	parts, err := s.objectDistributor.ListUploadParts(r.Context(), key, query.Get("uploadId"))
+	if err != nil {
+		writeError(w, r, err, errNoSuchUpload, "listing upload parts")
+		return
+	}
+
+	result := listPartsResult{
+		Xmlns:            s3Namespace,
+		Bucket:           s.config.Bucket,
+		Key:              key,
+		UploadID:         query.Get("uploadId"),
+		PartNumberMarker: marker,
+		MaxParts:         maxParts,
+		Parts:            make([]listedPart, 0),
+	}
+	for _, part := range parts {
+		if part.PartNumber <= marker {
+			continue
+		}
+		if len(result.Parts) == maxParts {
+			result.IsTruncated = true
+			break
+		}
+		result.Parts = append(result.Parts, listedPart{
+			PartNumber: part.PartNumber,
+			ETag:       strconv.Quote(part.ETag),
+			Size:       part.Size,
+		})
+		result.NextPartNumberMarker = part.PartNumber
+	}
+	writeXML(w, http.StatusOK, result)
+}
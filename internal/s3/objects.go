@@ -0,0 +1,366 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/httputil"
+)
+
+const (
+	userMetadataPrefix = "X-Amz-Meta-"
+	maxDeleteObjects   = 1000
+	// maxXMLBodySize bounds request bodies decoded as XML.
+	maxXMLBodySize = 2 << 20
+)
+
+// responseOverrides are query parameters replacing response headers of GetObject, mostly used with presigned URLs.
+var responseOverrides = map[string]string{
+	"response-content-type":        "Content-Type",
+	"response-content-language":    "Content-Language",
+	"response-expires":             "Expires",
+	"response-cache-control":       "Cache-Control",
+	"response-content-disposition": "Content-Disposition",
+	"response-content-encoding":    "Content-Encoding",
+}
+
+type copyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	LastModified string   `xml:"LastModified"`
+	ETag         string   `xml:"ETag"`
+}
+
+type deleteRequest struct {
+	Quiet   bool `xml:"Quiet"`
+	Objects []struct {
+		Key string `xml:"Key"`
+	} `xml:"Object"`
+}
+
+type deleteResult struct {
+	XMLName xml.Name        `xml:"DeleteResult"`
+	Xmlns   string          `xml:"xmlns,attr"`
+	Deleted []deletedObject `xml:"Deleted"`
+	Errors  []deleteError   `xml:"Error"`
+}
+
+type deletedObject struct {
+	Key string `xml:"Key"`
+}
+
+type deleteError struct {
+	Key     string `xml:"Key"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (s *server) putObject(w http.ResponseWriter, r *http.Request, key string) {
+	if r.ContentLength < 0 {
+		writeError(w, r, errMissingContentLength, nil, "")
+		return
+	}
+	metadata, err := httputil.ParseMetadata(r.Header, userMetadataPrefix)
+	if err != nil {
+		writeError(w, r, err, nil, "")
+		return
+	}
+
+	info, err := s.objectDistributor.PutObject(r.Context(), key, r.Body, r.ContentLength, distributor.PutOptions{
+		Metadata:    metadata,
+		IfMatch:     parseETags(r.Header.Get("If-Match")),
+		IfNoneMatch: parseETags(r.Header.Get("If-None-Match")),
+	})
+	if err != nil {
+		writeError(w, r, err, errNoSuchKey, "putting object")
+		return
+	}
+	w.Header().Set("ETag", strconv.Quote(info.ETag))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *server) copyObject(w http.ResponseWriter, r *http.Request, key string) {
+	sourceBucket, sourceKey, err := parseCopySource(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		writeError(w, r, err, nil, "")
+		return
+	}
+	if sourceBucket != s.config.Bucket {
+		writeError(w, r, errNoSuchBucket, nil, "")
+		return
+	}
+	if core.IsInternalObject(sourceKey) {
+		writeError(w, r, errAccessDenied, nil, "")
+		return
+	}
+
+	body, info, err := s.objectDistributor.GetObject(r.Context(), sourceKey, distributor.GetOptions{})
+	if err != nil {
+		writeError(w, r, err, errNoSuchKey, "getting copy source")
+		return
+	}
+	defer body.Close()
+	if err := checkPreconditions(r.Header, info, "X-Amz-Copy-Source-"); err != nil {
+		writeError(w, r, err, nil, "")
+		return
+	}
+
+	metadata := info.Metadata
+	if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+		if metadata, err = httputil.ParseMetadata(r.Header, userMetadataPrefix); err != nil {
+			writeError(w, r, err, nil, "")
+			return
+		}
+	}
+
+	copied, err := s.objectDistributor.PutObject(r.Context(), key, body, info.Size, distributor.PutOptions{Metadata: metadata})
+	if err != nil {
+		writeError(w, r, err, errNoSuchKey, "copying object")
+		return
+	}
+
+	writeXML(w, http.StatusOK, copyObjectResult{
+		LastModified: copied.LastModified.UTC().Format(timestampFormat),
+		ETag:         strconv.Quote(copied.ETag),
+	})
+}
+
+// getObject serves both GetObject and HeadObject.
+func (s *server) getObject(w http.ResponseWriter, r *http.Request, key string) {
+	info, err := s.objectDistributor.StatObject(r.Context(), key, distributor.GetOptions{})
+	if err != nil {
+		writeError(w, r, err, errNoSuchKey, "getting object info")
+		return
+	}
+	if err := checkPreconditions(r.Header, info, ""); err != nil {
+		if err == errNotModified {
+			writeValidatorHeaders(w, info)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writeError(w, r, err, nil, "")
+		return
+	}
+
+	rng, err := parseRange(r.Header.Get("Range"), info.Size)
+	if err != nil {
+		w.Header().Set("Content-Range", "bytes */"+strconv.FormatInt(info.Size, 10))
+		writeError(w, r, err, nil, "")
+		return
+	}
+
+	writeObjectHeaders(w, info)
+	for param, header := range responseOverrides {
+		if value := r.URL.Query().Get(param); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+
+	status := http.StatusOK
+	if rng != nil {
+		status = http.StatusPartialContent
+		w.Header().Set("Content-Length", strconv.FormatInt(rng.Length(), 10))
+		w.Header().Set("Content-Range", "bytes "+strconv.FormatInt(rng.Start, 10)+"-"+strconv.FormatInt(rng.End, 10)+"/"+strconv.FormatInt(info.Size, 10))
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		return
+	}
+
+	// Pinned to the stat'd ETag, so the body belongs to the headers written above.
+	body, _, err := s.objectDistributor.GetObject(r.Context(), key, distributor.GetOptions{Range: rng, MatchETag: info.ETag})
+	if errors.Is(err, core.ErrPreconditionFailed) {
+		// Overwritten since it was stat'ed, the client didn't ask for any precondition.
+		err = errServiceUnavailable
+	}
+	if err != nil {
+		writeError(w, r, err, errNoSuchKey, "getting object")
+		return
+	}
+	defer body.Close()
+
+	w.WriteHeader(status)
+	httputil.CopyBody(w, body, key)
+}
+
+func (s *server) deleteObject(w http.ResponseWriter, r *http.Request, key string) {
+	// Deleting a missing key succeeds in S3.
+	if err := s.objectDistributor.DeleteObject(r.Context(), key); err != nil && err != core.ErrNotFound {
+		writeError(w, r, err, errNoSuchKey, "deleting object")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) deleteObjects(w http.ResponseWriter, r *http.Request) {
+	var request deleteRequest
+	if err := xml.NewDecoder(io.LimitReader(r.Body, maxXMLBodySize)).Decode(&request); err != nil {
+		writeError(w, r, errMalformedXML, nil, "")
+		return
+	}
+	if len(request.Objects) == 0 || len(request.Objects) > maxDeleteObjects {
+		writeError(w, r, errMalformedXML, nil, "")
+		return
+	}
+
+	result := deleteResult{Xmlns: s3Namespace}
+	for _, object := range request.Objects {
+		err := error(errAccessDenied)
+		if !core.IsInternalObject(object.Key) {
+			err = s.objectDistributor.DeleteObject(r.Context(), object.Key)
+		}
+		if err != nil && err != core.ErrNotFound {
+			apiErr := toAPIError(err, errNoSuchKey)
+			result.Errors = append(result.Errors, deleteError{Key: object.Key, Code: apiErr.Code, Message: apiErr.Message})
+			continue
+		}
+		if !request.Quiet {
+			result.Deleted = append(result.Deleted, deletedObject{Key: object.Key})
+		}
+	}
+	writeXML(w, http.StatusOK, result)
+}
+
+func writeObjectHeaders(w http.ResponseWriter, info core.ObjectInfo) {
+	contentType := info.Metadata.ContentType
+	if contentType == "" {
+		contentType = "binary/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Accept-Ranges", "bytes")
+	if info.Metadata.ContentEncoding != "" {
+		w.Header().Set("Content-Encoding", info.Metadata.ContentEncoding)
+	}
+	if info.Metadata.ContentDisposition != "" {
+		w.Header().Set("Content-Disposition", info.Metadata.ContentDisposition)
+	}
+	for name, value := range info.Metadata.UserMetadata {
+		w.Header().Set(userMetadataPrefix+name, value)
+	}
+	writeValidatorHeaders(w, info)
+}
+
+func writeValidatorHeaders(w http.ResponseWriter, info core.ObjectInfo) {
+	if info.ETag != "" {
+		w.Header().Set("ETag", strconv.Quote(info.ETag))
+	}
+	if !info.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// parseCopySource parses the URL encoded "bucket/key" of X-Amz-Copy-Source, versions aren't supported.
+func parseCopySource(source string) (string, string, error) {
+	source, _, _ = strings.Cut(source, "?")
+	decoded, err := url.PathUnescape(source)
+	if err != nil {
+		return "", "", errInvalidCopySource
+	}
+	bucket, key := splitPath("/" + strings.TrimPrefix(decoded, "/"))
+	if bucket == "" || key == "" {
+		return "", "", errInvalidCopySource
+	}
+	return bucket, key, nil
+}
+
+func parseETags(header string) []string {
+	etags := make([]string, 0)
+	for _, etag := range strings.Split(header, ",") {
+		etag = strings.Trim(textproto.TrimString(etag), `"`)
+		if etag != "" {
+			etags = append(etags, etag)
+		}
+	}
+	return etags
+}
+
+// errNotModified is returned by checkPreconditions for reads which should be answered with 304.
+var errNotModified = &apiError{"NotModified", "Not Modified", http.StatusNotModified}
+
+// checkPreconditions evaluates the conditional headers in the order S3 does, prefix selects
+// the X-Amz-Copy-Source- variants of CopyObject.
+func checkPreconditions(header http.Header, info core.ObjectInfo, prefix string) error {
+	matches := func(value string) bool {
+		for _, etag := range parseETags(value) {
+			if etag == "*" || etag == info.ETag {
+				return true
+			}
+		}
+		return false
+	}
+	lastModified := info.LastModified.Truncate(time.Second)
+
+	ifMatch := header.Get(prefix + "If-Match")
+	if ifMatch != "" && !matches(ifMatch) {
+		return errPreconditionFailed
+	}
+	if since, err := http.ParseTime(header.Get(prefix + "If-Unmodified-Since")); ifMatch == "" && err == nil && lastModified.After(since) {
+		return errPreconditionFailed
+	}
+
+	notModified := errNotModified
+	if prefix != "" {
+		notModified = errPreconditionFailed
+	}
+	ifNoneMatch := header.Get(prefix + "If-None-Match")
+	if ifNoneMatch != "" && matches(ifNoneMatch) {
+		return notModified
+	}
+	if since, err := http.ParseTime(header.Get(prefix + "If-Modified-Since")); ifNoneMatch == "" && err == nil && !lastModified.After(since) {
+		return notModified
+	}
+	return nil
+}
+
+// parseRange parses a single byte range, S3 ignores anything else.
+func parseRange(header string, size int64) (*core.ByteRange, error) {
+	spec := strings.TrimPrefix(header, "bytes=")
+	if header == "" || spec == header || strings.Contains(spec, ",") {
+		return nil, nil
+	}
+	first, last, ok := strings.Cut(spec, "-")
+	if !ok {
+		return nil, nil
+	}
+
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, nil
+		}
+		if size == 0 {
+			return nil, errInvalidRange
+		}
+		if n > size {
+			n = size
+		}
+		return &core.ByteRange{Start: size - n, End: size - 1}, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return nil, nil
+		}
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return nil, errInvalidRange
+	}
+	return &core.ByteRange{Start: start, End: end}, nil
+}
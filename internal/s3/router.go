@@ -0,0 +1,131 @@
+// Package s3 serves a subset of the S3 API, path-style addressed, on top of the object distributor.
+// A single bucket is exposed, its keys are the object IDs the distributor shards across storages.
+package s3
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+)
+
+const (
+	maxKeyLength = 1024
+	s3Namespace  = "http://s3.amazonaws.com/doc/2006-03-01/"
+	// timestampFormat is the ISO 8601 format S3 uses for timestamps in XML bodies.
+	timestampFormat = "2006-01-02T15:04:05.000Z"
+)
+
+type Config struct {
+	Bucket string
+	Region string
+	// Credentials maps access keys onto their secret keys.
+	Credentials map[string]string
+}
+
+type server struct {
+	objectDistributor *distributor.ObjectDistributor
+	config            Config
+	auth              *authenticator
+}
+
+func Router(objectDistributor *distributor.ObjectDistributor, config Config) http.Handler {
+	return &server{
+		objectDistributor: objectDistributor,
+		config:            config,
+		auth: &authenticator{
+			credentials: config.Credentials,
+			now:         time.Now,
+		},
+	}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := s.auth.authenticate(r); err != nil {
+		writeError(w, r, err, errAccessDenied, "authenticating request")
+		return
+	}
+
+	bucket, key := splitPath(r.URL.Path)
+	switch {
+	case bucket == "":
+		if r.Method != http.MethodGet {
+			writeError(w, r, errMethodNotAllowed, nil, "")
+			return
+		}
+		s.listBuckets(w, r)
+	case bucket != s.config.Bucket:
+		writeError(w, r, errNoSuchBucket, nil, "")
+	case key == "":
+		s.serveBucket(w, r)
+	case len(key) > maxKeyLength:
+		writeError(w, r, errKeyTooLong, nil, "")
+	case core.IsInternalObject(key):
+		writeError(w, r, errAccessDenied, nil, "")
+	default:
+		s.serveObject(w, r, key)
+	}
+}
+
+func (s *server) serveBucket(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch r.Method {
+	case http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		switch {
+		case query.Has("location"):
+			s.getBucketLocation(w, r)
+		case query.Has("uploads"), query.Has("versions"), query.Has("acl"), query.Has("policy"):
+			writeError(w, r, errNotImplemented, nil, "")
+		case query.Get("list-type") == "2":
+			s.listObjectsV2(w, r)
+		default:
+			s.listObjectsV1(w, r)
+		}
+	case http.MethodPost:
+		if !query.Has("delete") {
+			writeError(w, r, errNotImplemented, nil, "")
+			return
+		}
+		s.deleteObjects(w, r)
+	case http.MethodPut:
+		writeError(w, r, errBucketAlreadyOwnedByYou, nil, "")
+	default:
+		writeError(w, r, errMethodNotAllowed, nil, "")
+	}
+}
+
+func (s *server) serveObject(w http.ResponseWriter, r *http.Request, key string) {
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		s.uploadPart(w, r, key)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.copyObject(w, r, key)
+	case r.Method == http.MethodPut:
+		s.putObject(w, r, key)
+	case r.Method == http.MethodGet && query.Has("uploadId"):
+		s.listParts(w, r, key)
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		s.getObject(w, r, key)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		s.abortMultipartUpload(w, r, key)
+	case r.Method == http.MethodDelete:
+		s.deleteObject(w, r, key)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.createMultipartUpload(w, r, key)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		s.completeMultipartUpload(w, r, key)
+	default:
+		writeError(w, r, errMethodNotAllowed, nil, "")
+	}
+}
+
+// splitPath splits a path-style request path into the bucket and the object key.
+func splitPath(path string) (string, string) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return bucket, key
+}
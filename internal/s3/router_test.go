@@ -0,0 +1,314 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/signer"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testBucket    = "objects"
+	testAccessKey = "access"
+	testSecretKey = "secret"
+)
+
+var testStorageIDs = []string{"a", "b", "c"}
+
+// sizedStorage reads exactly as many bytes as announced like the Minio client, which ignores an error
+// returned along with the last of them.
+type sizedStorage struct {
+	*memory.ObjectStorage
+}
+
+func (s sizedStorage) Put(ctx context.Context, objectID string, r io.Reader, size int64, metadata core.ObjectMetadata) (core.ObjectInfo, error) {
+	if size < 0 {
+		return s.ObjectStorage.Put(ctx, objectID, r, size, metadata)
+	}
+	blob := make([]byte, size)
+	if _, err := io.ReadFull(r, blob); err != nil {
+		return core.ObjectInfo{}, err
+	}
+	return s.ObjectStorage.Put(ctx, objectID, bytes.NewReader(blob), size, metadata)
+}
+
+func newTestServer(t *testing.T) (*distributor.ObjectDistributor, *httptest.Server) {
+	objectDistributor := distributor.NewObjectDistributor(util.NewConsistentHashStorageSelector(), distributor.WithReplication(2, 2))
+	for _, storageID := range testStorageIDs {
+		objectDistributor.AddStorage(storageID, sizedStorage{memory.NewObjectStorage()}, 1)
+	}
+	server := httptest.NewServer(Router(objectDistributor, Config{
+		Bucket:      testBucket,
+		Region:      "us-east-1",
+		Credentials: map[string]string{testAccessKey: testSecretKey},
+	}))
+	t.Cleanup(server.Close)
+	return objectDistributor, server
+}
+
+func newTestClient(t *testing.T, secretKey string) *minio.Client {
+	_, server := newTestServer(t)
+
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	client, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(testAccessKey, secretKey, ""),
+		Region:       "us-east-1",
+		BucketLookup: minio.BucketLookupPath,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func putString(client *minio.Client, key, blob string, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	return client.PutObject(context.Background(), testBucket, key, strings.NewReader(blob), int64(len(blob)), opts)
+}
+
+func getString(t *testing.T, client *minio.Client, key string, opts minio.GetObjectOptions) string {
+	object, err := client.GetObject(context.Background(), testBucket, key, opts)
+	require.NoError(t, err)
+	defer object.Close()
+
+	blob, err := io.ReadAll(object)
+	require.NoError(t, err)
+	return string(blob)
+}
+
+func TestRouter(t *testing.T) {
+	client := newTestClient(t, testSecretKey)
+
+	t.Run("object put with streaming signature is returned with its metadata", func(t *testing.T) {
+		_, err := putString(client, "dir/a.txt", "hello world", minio.PutObjectOptions{
+			ContentType:  "text/plain",
+			UserMetadata: map[string]string{"Owner": "team-a"},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "hello world", getString(t, client, "dir/a.txt", minio.GetObjectOptions{}))
+
+		info, err := client.StatObject(context.Background(), testBucket, "dir/a.txt", minio.StatObjectOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(11), info.Size)
+		assert.Equal(t, "text/plain", info.ContentType)
+		assert.Equal(t, "team-a", info.UserMetadata["Owner"])
+	})
+
+	t.Run("object put with unsigned payload and Content-MD5 is stored", func(t *testing.T) {
+		_, err := putString(client, "dir/b.txt", "unsigned", minio.PutObjectOptions{
+			DisableContentSha256: true,
+			SendContentMd5:       true,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "unsigned", getString(t, client, "dir/b.txt", minio.GetObjectOptions{}))
+	})
+
+	t.Run("object range is returned", func(t *testing.T) {
+		opts := minio.GetObjectOptions{}
+		require.NoError(t, opts.SetRange(6, 10))
+		assert.Equal(t, "world", getString(t, client, "dir/a.txt", opts))
+	})
+
+	t.Run("objects are listed with common prefixes", func(t *testing.T) {
+		_, err := putString(client, "root.txt", "root", minio.PutObjectOptions{})
+		require.NoError(t, err)
+
+		keys := make([]string, 0)
+		for info := range client.ListObjects(context.Background(), testBucket, minio.ListObjectsOptions{}) {
+			require.NoError(t, info.Err)
+			keys = append(keys, info.Key)
+		}
+		assert.ElementsMatch(t, []string{"dir/", "root.txt"}, keys)
+
+		keys = keys[:0]
+		for info := range client.ListObjects(context.Background(), testBucket, minio.ListObjectsOptions{Recursive: true, MaxKeys: 1}) {
+			require.NoError(t, info.Err)
+			keys = append(keys, info.Key)
+		}
+		assert.Equal(t, []string{"dir/a.txt", "dir/b.txt", "root.txt"}, keys)
+	})
+
+	t.Run("object is copied", func(t *testing.T) {
+		_, err := client.CopyObject(context.Background(),
+			minio.CopyDestOptions{Bucket: testBucket, Object: "copy.txt"},
+			minio.CopySrcOptions{Bucket: testBucket, Object: "dir/a.txt"},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", getString(t, client, "copy.txt", minio.GetObjectOptions{}))
+	})
+
+	t.Run("object is assembled from multipart upload", func(t *testing.T) {
+		core := minio.Core{Client: client}
+		uploadID, err := core.NewMultipartUpload(context.Background(), testBucket, "multipart.txt", minio.PutObjectOptions{})
+		require.NoError(t, err)
+
+		parts := make([]minio.CompletePart, 0)
+		for i, blob := range []string{"first ", "second"} {
+			part, err := core.PutObjectPart(context.Background(), testBucket, "multipart.txt", uploadID, i+1, strings.NewReader(blob), int64(len(blob)), minio.PutObjectPartOptions{})
+			require.NoError(t, err)
+			parts = append(parts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+		}
+
+		listed, err := core.ListObjectParts(context.Background(), testBucket, "multipart.txt", uploadID, 0, 1000)
+		require.NoError(t, err)
+		assert.Len(t, listed.ObjectParts, 2)
+
+		_, err = core.CompleteMultipartUpload(context.Background(), testBucket, "multipart.txt", uploadID, parts, minio.PutObjectOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "first second", getString(t, client, "multipart.txt", minio.GetObjectOptions{}))
+	})
+
+	t.Run("presigned URL serves object without headers", func(t *testing.T) {
+		presigned, err := client.PresignedGetObject(context.Background(), testBucket, "dir/a.txt", time.Minute, nil)
+		require.NoError(t, err)
+
+		resp, err := http.Get(presigned.String())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		blob, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "hello world", string(blob))
+	})
+
+	t.Run("objects are deleted", func(t *testing.T) {
+		require.NoError(t, client.RemoveObject(context.Background(), testBucket, "copy.txt", minio.RemoveObjectOptions{}))
+
+		objects := make(chan minio.ObjectInfo, 2)
+		objects <- minio.ObjectInfo{Key: "dir/a.txt"}
+		objects <- minio.ObjectInfo{Key: "dir/b.txt"}
+		close(objects)
+		for err := range client.RemoveObjects(context.Background(), testBucket, objects, minio.RemoveObjectsOptions{}) {
+			require.NoError(t, err.Err)
+		}
+
+		_, err := client.StatObject(context.Background(), testBucket, "dir/a.txt", minio.StatObjectOptions{})
+		assert.Equal(t, "NoSuchKey", minio.ToErrorResponse(err).Code)
+	})
+
+	t.Run("when secret key is wrong, request is rejected", func(t *testing.T) {
+		client := newTestClient(t, "wrong")
+		_, err := putString(client, "key", "blob", minio.PutObjectOptions{})
+		assert.Equal(t, "SignatureDoesNotMatch", minio.ToErrorResponse(err).Code)
+	})
+
+	t.Run("when user metadata is too large, should return metadata too large", func(t *testing.T) {
+		_, err := putString(client, "large", "blob", minio.PutObjectOptions{
+			UserMetadata: map[string]string{"Large": strings.Repeat("a", 2<<10)},
+		})
+		assert.Equal(t, "MetadataTooLarge", minio.ToErrorResponse(err).Code)
+	})
+
+	t.Run("when bucket is unknown, should return no such bucket", func(t *testing.T) {
+		_, err := client.PutObject(context.Background(), "other", "key", bytes.NewReader(nil), 0, minio.PutObjectOptions{})
+		assert.Equal(t, "NoSuchBucket", minio.ToErrorResponse(err).Code)
+	})
+}
+
+// sha256Hasher adapts sha256 to the hasher the streaming signer takes.
+type sha256Hasher struct {
+	hash.Hash
+}
+
+func (sha256Hasher) Close() {}
+
+func TestRouterPayloadVerification(t *testing.T) {
+	objectDistributor, server := newTestServer(t)
+
+	newRequest := func(t *testing.T, key, blob string) *http.Request {
+		req, err := http.NewRequest(http.MethodPut, server.URL+"/"+testBucket+"/"+key, strings.NewReader(blob))
+		require.NoError(t, err)
+		return req
+	}
+	send := func(t *testing.T, req *http.Request) (int, string) {
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+	assertNotStored := func(t *testing.T, key string) {
+		for _, storageID := range testStorageIDs {
+			storage, ok := objectDistributor.Storage(storageID)
+			require.True(t, ok)
+			_, err := storage.Stat(context.Background(), key)
+			assert.ErrorIs(t, err, core.ErrNotFound, storageID)
+		}
+	}
+
+	t.Run("when payload hash matches, object is stored", func(t *testing.T) {
+		req := newRequest(t, "signed.txt", "hello world")
+		req.Header.Set("X-Amz-Content-Sha256", hexSHA256([]byte("hello world")))
+		status, _ := send(t, signer.SignV4(*req, testAccessKey, testSecretKey, "", "us-east-1"))
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("when x-amz-content-sha256 doesn't match the body, object isn't stored", func(t *testing.T) {
+		req := newRequest(t, "sha256.txt", "hello world")
+		req.Header.Set("X-Amz-Content-Sha256", hexSHA256([]byte("hello there")))
+		status, body := send(t, signer.SignV4(*req, testAccessKey, testSecretKey, "", "us-east-1"))
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, "XAmzContentSHA256Mismatch")
+		assertNotStored(t, "sha256.txt")
+	})
+
+	t.Run("when Content-MD5 doesn't match the body, object isn't stored", func(t *testing.T) {
+		sum := md5.Sum([]byte("hello there"))
+		req := newRequest(t, "md5.txt", "hello world")
+		req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+		req.Header.Set("Content-Md5", base64.StdEncoding.EncodeToString(sum[:]))
+		status, body := send(t, signer.SignV4(*req, testAccessKey, testSecretKey, "", "us-east-1"))
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, body, "BadDigest")
+		assertNotStored(t, "md5.txt")
+	})
+
+	for name, tamper := range map[string]func(encoded []byte) []byte{
+		"data chunk": func(encoded []byte) []byte {
+			return bytes.Replace(encoded, []byte("hello"), []byte("jello"), 1)
+		},
+		"final chunk": func(encoded []byte) []byte {
+			final := bytes.LastIndex(encoded, []byte("0;chunk-signature="))
+			tampered := append([]byte{}, encoded...)
+			tampered[final+len("0;chunk-signature=")] ^= 1
+			return tampered
+		},
+	} {
+		tamper := tamper
+		t.Run("when the "+name+" doesn't match its signature, object isn't stored", func(t *testing.T) {
+			key := strings.ReplaceAll(name, " ", "-") + ".txt"
+			req := newRequest(t, key, "hello world")
+			req = signer.StreamingSignV4(req, testAccessKey, testSecretKey, "", "us-east-1", 11, time.Now().UTC(), sha256Hasher{sha256.New()})
+			encoded, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			req.Body = io.NopCloser(bytes.NewReader(tamper(encoded)))
+			status, body := send(t, req)
+
+			assert.Equal(t, http.StatusForbidden, status)
+			assert.Contains(t, body, "SignatureDoesNotMatch")
+			assertNotStored(t, key)
+		})
+	}
+}
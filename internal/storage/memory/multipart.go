@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"sort"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+)
+
+type upload struct {
+	objectID string
+	metadata core.ObjectMetadata
+	parts    map[int]part
+}
+
+type part struct {
+	blob []byte
+	info core.PartInfo
+}
+
+func (o *ObjectStorage) InitiateMultipart(ctx context.Context, objectID string, metadata core.ObjectMetadata) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	uploadID := hex.EncodeToString(id)
+
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	o.uploads[uploadID] = &upload{
+		objectID: objectID,
+		metadata: metadata,
+		parts:    make(map[int]part),
+	}
+	return uploadID, nil
+}
+
+func (o *ObjectStorage) PutPart(ctx context.Context, objectID, uploadID string, partNumber int, r io.Reader, size int64) (core.PartInfo, error) {
+	blob, err := io.ReadAll(r)
+	if err != nil {
+		return core.PartInfo{}, err
+	}
+	checksum := md5.Sum(blob)
+
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	u, err := o.getUpload(objectID, uploadID)
+	if err != nil {
+		return core.PartInfo{}, err
+	}
+	info := core.PartInfo{
+		PartNumber: partNumber,
+		ETag:       hex.EncodeToString(checksum[:]),
+		Size:       int64(len(blob)),
+	}
+	u.parts[partNumber] = part{blob: blob, info: info}
+	return info, nil
+}
+
+func (o *ObjectStorage) ListParts(ctx context.Context, objectID, uploadID string) ([]core.PartInfo, error) {
+	o.l.RLock()
+	defer o.l.RUnlock()
+
+	u, err := o.getUpload(objectID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]core.PartInfo, 0, len(u.parts))
+	for _, p := range u.parts {
+		parts = append(parts, p.info)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].PartNumber < parts[j].PartNumber
+	})
+	return parts, nil
+}
+
+func (o *ObjectStorage) CompleteMultipart(ctx context.Context, objectID, uploadID string, parts []core.PartInfo) (core.ObjectInfo, error) {
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	u, err := o.getUpload(objectID, uploadID)
+	if err != nil {
+		return core.ObjectInfo{}, err
+	}
+
+	var blob bytes.Buffer
+	for i, p := range parts {
+		uploaded, ok := u.parts[p.PartNumber]
+		if !ok || uploaded.info.ETag != p.ETag || (i > 0 && p.PartNumber <= parts[i-1].PartNumber) {
+			return core.ObjectInfo{}, core.ErrInvalidPart
+		}
+		blob.Write(uploaded.blob)
+	}
+
+	delete(o.uploads, uploadID)
+	return o.store(objectID, blob.Bytes(), u.metadata), nil
+}
+
+func (o *ObjectStorage) AbortMultipart(ctx context.Context, objectID, uploadID string) error {
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	if _, err := o.getUpload(objectID, uploadID); err != nil {
+		return err
+	}
+	delete(o.uploads, uploadID)
+	return nil
+}
+
+// getUpload has to be called with the lock held.
+func (o *ObjectStorage) getUpload(objectID, uploadID string) (*upload, error) {
+	u, ok := o.uploads[uploadID]
+	if !ok || u.objectID != objectID {
+		return nil, core.ErrNotFound
+	}
+	return u, nil
+}
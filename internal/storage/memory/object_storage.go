@@ -1,34 +1,136 @@
 package memory
 
 import (
+	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/spacelift-io/homework-object-storage/internal/core"
 )
 
 type ObjectStorage struct {
-	database map[string][]byte
+	database map[string]object
+	uploads  map[string]*upload
+	l        sync.RWMutex
+}
+
+type object struct {
+	blob []byte
+	info core.ObjectInfo
 }
 
 func NewObjectStorage() *ObjectStorage {
 	return &ObjectStorage{
-		database: make(map[string][]byte),
+		database: make(map[string]object),
+		uploads:  make(map[string]*upload),
 	}
 }
 
-func (o *ObjectStorage) Put(ctx context.Context, objectID string, object []byte) error {
-	o.database[objectID] = object
-	return nil
+func (o *ObjectStorage) Put(ctx context.Context, objectID string, r io.Reader, size int64, metadata core.ObjectMetadata) (core.ObjectInfo, error) {
+	blob, err := io.ReadAll(r)
+	if err != nil {
+		return core.ObjectInfo{}, err
+	}
+
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	return o.store(objectID, blob, metadata), nil
+}
+
+// store has to be called with the lock held.
+func (o *ObjectStorage) store(objectID string, blob []byte, metadata core.ObjectMetadata) core.ObjectInfo {
+	checksum := md5.Sum(blob)
+	o.database[objectID] = object{
+		blob: blob,
+		info: core.ObjectInfo{
+			ID:           objectID,
+			Size:         int64(len(blob)),
+			ETag:         hex.EncodeToString(checksum[:]),
+			LastModified: time.Now(),
+			Metadata:     metadata,
+		},
+	}
+	return o.database[objectID].info
+}
+
+func (o *ObjectStorage) Get(ctx context.Context, objectID string, opts core.GetOptions) (io.ReadCloser, core.ObjectInfo, error) {
+	o.l.RLock()
+	defer o.l.RUnlock()
+
+	obj, ok := o.database[objectID]
+	if !ok {
+		return nil, core.ObjectInfo{}, core.ErrNotFound
+	}
+	if opts.MatchETag != "" && opts.MatchETag != obj.info.ETag {
+		return nil, core.ObjectInfo{}, core.ErrPreconditionFailed
+	}
+
+	blob := obj.blob
+	if opts.Range != nil {
+		if opts.Range.Start >= int64(len(blob)) {
+			return nil, core.ObjectInfo{}, core.ErrInvalidRange
+		}
+		end := opts.Range.End + 1
+		if end > int64(len(blob)) {
+			end = int64(len(blob))
+		}
+		blob = blob[opts.Range.Start:end]
+	}
+	return io.NopCloser(bytes.NewReader(blob)), obj.info, nil
 }
 
-func (o *ObjectStorage) Get(ctx context.Context, objectID string) ([]byte, error) {
-	object, ok := o.database[objectID]
+func (o *ObjectStorage) Stat(ctx context.Context, objectID string) (core.ObjectInfo, error) {
+	o.l.RLock()
+	defer o.l.RUnlock()
+
+	obj, ok := o.database[objectID]
 	if !ok {
-		return nil, core.ErrNotFound
+		return core.ObjectInfo{}, core.ErrNotFound
+	}
+	return obj.info, nil
+}
+
+func (o *ObjectStorage) Delete(ctx context.Context, objectID string) error {
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	if _, ok := o.database[objectID]; !ok {
+		return core.ErrNotFound
 	}
-	return object, nil
+	delete(o.database, objectID)
+	return nil
+}
+
+func (o *ObjectStorage) List(ctx context.Context, opts core.ListOptions) ([]core.ObjectInfo, error) {
+	o.l.RLock()
+	defer o.l.RUnlock()
+
+	objects := make([]core.ObjectInfo, 0)
+	for objectID, obj := range o.database {
+		if strings.HasPrefix(objectID, opts.Prefix) && objectID > opts.StartAfter {
+			objects = append(objects, obj.info)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].ID < objects[j].ID
+	})
+
+	if opts.Limit > 0 && len(objects) > opts.Limit {
+		objects = objects[:opts.Limit]
+	}
+	return objects, nil
 }
 
 func (o *ObjectStorage) ObjectCount() int {
+	o.l.RLock()
+	defer o.l.RUnlock()
+
 	return len(o.database)
 }
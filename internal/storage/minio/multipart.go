@@ -0,0 +1,91 @@
+package minio
+
+import (
+	"context"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/tracing"
+)
+
+// maxListedParts is the page size S3 compatible storages cap part listings at.
+const maxListedParts = 1000
+
+func (o *ObjectStorage) InitiateMultipart(ctx context.Context, objectID string, metadata core.ObjectMetadata) (_ string, err error) {
+	ctx, done := o.instrument(ctx, "initiate_multipart", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	return o.core().NewMultipartUpload(ctx, o.defaultBucket, objectID, toPutOptions(metadata))
+}
+
+func (o *ObjectStorage) PutPart(ctx context.Context, objectID, uploadID string, partNumber int, r io.Reader, size int64) (_ core.PartInfo, err error) {
+	ctx, done := o.instrument(ctx, "put_part", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	part, err := o.core().PutObjectPart(ctx, o.defaultBucket, objectID, uploadID, partNumber, r, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return core.PartInfo{}, toStorageError(err)
+	}
+	return toPartInfo(part), nil
+}
+
+func (o *ObjectStorage) ListParts(ctx context.Context, objectID, uploadID string) (_ []core.PartInfo, err error) {
+	ctx, done := o.instrument(ctx, "list_parts", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	parts := make([]core.PartInfo, 0)
+	marker := 0
+	for {
+		result, err := o.core().ListObjectParts(ctx, o.defaultBucket, objectID, uploadID, marker, maxListedParts)
+		if err != nil {
+			return nil, toStorageError(err)
+		}
+		for _, part := range result.ObjectParts {
+			parts = append(parts, toPartInfo(part))
+		}
+		if !result.IsTruncated {
+			return parts, nil
+		}
+		marker = result.NextPartNumberMarker
+	}
+}
+
+func (o *ObjectStorage) CompleteMultipart(ctx context.Context, objectID, uploadID string, parts []core.PartInfo) (_ core.ObjectInfo, err error) {
+	ctx, done := o.instrument(ctx, "complete_multipart", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	completeParts := make([]minio.CompletePart, 0, len(parts))
+	for _, part := range parts {
+		completeParts = append(completeParts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+
+	if _, err := o.core().CompleteMultipartUpload(ctx, o.defaultBucket, objectID, uploadID, completeParts, minio.PutObjectOptions{}); err != nil {
+		return core.ObjectInfo{}, toStorageError(err)
+	}
+	return o.Stat(ctx, objectID)
+}
+
+func (o *ObjectStorage) AbortMultipart(ctx context.Context, objectID, uploadID string) (err error) {
+	ctx, done := o.instrument(ctx, "abort_multipart", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	// Aborting succeeds for unknown uploads, so existence has to be checked upfront.
+	if _, err := o.core().ListObjectParts(ctx, o.defaultBucket, objectID, uploadID, 0, 1); err != nil {
+		return toStorageError(err)
+	}
+
+	return toStorageError(o.core().AbortMultipartUpload(ctx, o.defaultBucket, objectID, uploadID))
+}
+
+func (o *ObjectStorage) core() minio.Core {
+	return minio.Core{Client: o.minioClient}
+}
+
+func toPartInfo(part minio.ObjectPart) core.PartInfo {
+	return core.PartInfo{
+		PartNumber: part.PartNumber,
+		ETag:       part.ETag,
+		Size:       part.Size,
+	}
+}
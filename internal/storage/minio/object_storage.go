@@ -1,69 +1,198 @@
 package minio
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/metrics"
+	"github.com/spacelift-io/homework-object-storage/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type ObjectStorage struct {
 	minioClient   *minio.Client
 	defaultBucket string
+	// address labels the metrics of requests to this node.
+	address string
+	// healthCheckInterval is accessed atomically, it can change while health checks run.
+	healthCheckInterval int64
 }
 
-const errKeyNoSuchKey = "NoSuchKey"
+const (
+	errKeyNoSuchKey        = "NoSuchKey"
+	errKeyNoSuchUpload     = "NoSuchUpload"
+	errKeyInvalidRange     = "InvalidRange"
+	errKeyInvalidPart      = "InvalidPart"
+	errKeyInvalidPartOrder = "InvalidPartOrder"
+	errKeyEntityTooSmall   = "EntityTooSmall"
+	errKeyPrecondition     = "PreconditionFailed"
+)
+
+// unknownSizePartSize bounds the buffer minio allocates for uploads of unknown size,
+// which would otherwise default to hundreds of megabytes per upload.
+const unknownSizePartSize = 16 << 20
+
+const DefaultHealthCheckInterval = 3 * time.Second
+
+type Option func(o *ObjectStorage)
 
-const defaultBucketName = "default"
+// WithHealthCheckInterval sets the interval of the Minio client health checks backing Online.
+func WithHealthCheckInterval(interval time.Duration) Option {
+	return func(o *ObjectStorage) {
+		o.healthCheckInterval = int64(interval)
+	}
+}
 
-var defaultHealthCheckDuration = 3 * time.Second
+// SetHealthCheckInterval changes the interval for subsequent health checks.
+func (o *ObjectStorage) SetHealthCheckInterval(interval time.Duration) {
+	atomic.StoreInt64(&o.healthCheckInterval, int64(interval))
+}
 
-func NewObjectStorage(ctx context.Context, minioClient *minio.Client) (*ObjectStorage, error) {
-	bucketExist, err := minioClient.BucketExists(ctx, defaultBucketName)
+// NewObjectStorage creates a storage keeping objects in the bucket, which is created if missing.
+func NewObjectStorage(ctx context.Context, minioClient *minio.Client, bucket string, opts ...Option) (*ObjectStorage, error) {
+	bucketExist, err := minioClient.BucketExists(ctx, bucket)
 	if err != nil {
 		return nil, fmt.Errorf("checking if bucket exists: %w", err)
 	}
 
 	if !bucketExist {
-		if err := minioClient.MakeBucket(ctx, defaultBucketName, minio.MakeBucketOptions{}); err != nil {
+		if err := minioClient.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
 			return nil, fmt.Errorf("creating bucket: %w", err)
 		}
 	}
-	return &ObjectStorage{
-		minioClient:   minioClient,
-		defaultBucket: defaultBucketName,
-	}, nil
+	o := &ObjectStorage{
+		minioClient:         minioClient,
+		defaultBucket:       bucket,
+		address:             minioClient.EndpointURL().Host,
+		healthCheckInterval: int64(DefaultHealthCheckInterval),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o, nil
 }
 
-func (o *ObjectStorage) Put(ctx context.Context, objectID string, object []byte) error {
-	_, err := o.minioClient.PutObject(ctx, o.defaultBucket, objectID, bytes.NewReader(object), int64(len(object)), minio.PutObjectOptions{})
-	return err
+func (o *ObjectStorage) Put(ctx context.Context, objectID string, r io.Reader, size int64, metadata core.ObjectMetadata) (_ core.ObjectInfo, err error) {
+	ctx, done := o.instrument(ctx, "put", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	opts := toPutOptions(metadata)
+	if size < 0 {
+		opts.PartSize = unknownSizePartSize
+	}
+
+	uploaded, err := o.minioClient.PutObject(ctx, o.defaultBucket, objectID, r, size, opts)
+	if err != nil {
+		return core.ObjectInfo{}, err
+	}
+	return core.ObjectInfo{
+		ID:           objectID,
+		Size:         uploaded.Size,
+		ETag:         uploaded.ETag,
+		LastModified: uploaded.LastModified,
+		Metadata:     metadata,
+	}, nil
 }
 
-func (o *ObjectStorage) Get(ctx context.Context, objectID string) ([]byte, error) {
-	obj, err := o.minioClient.GetObject(ctx, o.defaultBucket, objectID, minio.GetObjectOptions{})
+func (o *ObjectStorage) Get(ctx context.Context, objectID string, opts core.GetOptions) (_ io.ReadCloser, _ core.ObjectInfo, err error) {
+	ctx, done := o.instrument(ctx, "get", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	getOpts := minio.GetObjectOptions{}
+	if opts.Range != nil {
+		if err := getOpts.SetRange(opts.Range.Start, opts.Range.End); err != nil {
+			return nil, core.ObjectInfo{}, err
+		}
+	}
+	if opts.MatchETag != "" {
+		if err := getOpts.SetMatchETag(opts.MatchETag); err != nil {
+			return nil, core.ObjectInfo{}, err
+		}
+	}
+
+	// Core issues the request right away and exposes headers, which carry the full size of ranged objects.
+	body, info, header, err := o.core().GetObject(ctx, o.defaultBucket, objectID, getOpts)
 	if err != nil {
-		return nil, err
+		return nil, core.ObjectInfo{}, toStorageError(err)
 	}
 
-	blob, err := io.ReadAll(obj)
+	objInfo := toObjectInfo(objectID, info)
+	if opts.Range != nil {
+		size, err := parseContentRangeSize(header.Get("Content-Range"))
+		if err != nil {
+			body.Close()
+			return nil, core.ObjectInfo{}, err
+		}
+		objInfo.Size = size
+	}
+	return body, objInfo, nil
+}
+
+func (o *ObjectStorage) Stat(ctx context.Context, objectID string) (_ core.ObjectInfo, err error) {
+	ctx, done := o.instrument(ctx, "stat", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	info, err := o.minioClient.StatObject(ctx, o.defaultBucket, objectID, minio.StatObjectOptions{})
 	if err != nil {
-		if minioErr, ok := err.(minio.ErrorResponse); ok {
-			if minioErr.Code == errKeyNoSuchKey {
-				return nil, core.ErrNotFound
-			}
+		return core.ObjectInfo{}, toStorageError(err)
+	}
+
+	return toObjectInfo(objectID, info), nil
+}
+
+func (o *ObjectStorage) Delete(ctx context.Context, objectID string) (err error) {
+	ctx, done := o.instrument(ctx, "delete", tracing.ObjectID(objectID))
+	defer done(&err)
+
+	// RemoveObject succeeds for missing keys, so existence has to be checked upfront.
+	if _, err := o.Stat(ctx, objectID); err != nil {
+		return err
+	}
+
+	return o.minioClient.RemoveObject(ctx, o.defaultBucket, objectID, minio.RemoveObjectOptions{})
+}
+
+func (o *ObjectStorage) List(ctx context.Context, opts core.ListOptions) (_ []core.ObjectInfo, err error) {
+	ctx, done := o.instrument(ctx, "list")
+	defer done(&err)
+
+	// Cancelling stops minio from fetching further pages once the limit is reached.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	objects := make([]core.ObjectInfo, 0)
+	for info := range o.minioClient.ListObjects(ctx, o.defaultBucket, minio.ListObjectsOptions{
+		Prefix:     opts.Prefix,
+		StartAfter: opts.StartAfter,
+		MaxKeys:    opts.Limit,
+		Recursive:  true,
+	}) {
+		if info.Err != nil {
+			return nil, info.Err
+		}
+
+		objects = append(objects, toObjectInfo(info.Key, info))
+		if opts.Limit > 0 && len(objects) >= opts.Limit {
+			break
 		}
-		return nil, err
 	}
-	return blob, nil
+	return objects, nil
 }
 
-func (o *ObjectStorage) Online() (bool, error) {
-	cancelFn, err := o.minioClient.HealthCheck(defaultHealthCheckDuration)
+func (o *ObjectStorage) Online() (_ bool, err error) {
+	// Health checks run in the background, they are measured but not traced.
+	defer func(start time.Time) {
+		metrics.ObserveStorageRequest(o.address, "health_check", start, err)
+	}(time.Now())
+
+	cancelFn, err := o.minioClient.HealthCheck(time.Duration(atomic.LoadInt64(&o.healthCheckInterval)))
 	if err != nil {
 		return false, err
 	}
@@ -71,3 +200,66 @@ func (o *ObjectStorage) Online() (bool, error) {
 
 	return o.minioClient.IsOnline(), nil
 }
+
+// instrument starts measuring and tracing a request to the node, done records it once it returns
+// and takes a pointer to its named error result.
+func (o *ObjectStorage) instrument(ctx context.Context, operation string, attrs ...attribute.KeyValue) (_ context.Context, done func(err *error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "minio."+operation, append(attrs, tracing.Address(o.address))...)
+	return ctx, func(err *error) {
+		metrics.ObserveStorageRequest(o.address, operation, start, *err)
+		tracing.End(span, *err)
+	}
+}
+
+func toObjectInfo(objectID string, info minio.ObjectInfo) core.ObjectInfo {
+	return core.ObjectInfo{
+		ID:           objectID,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+		Metadata: core.ObjectMetadata{
+			ContentType:        info.ContentType,
+			ContentEncoding:    info.Metadata.Get("Content-Encoding"),
+			ContentDisposition: info.Metadata.Get("Content-Disposition"),
+			UserMetadata:       info.UserMetadata,
+		},
+	}
+}
+
+func toPutOptions(metadata core.ObjectMetadata) minio.PutObjectOptions {
+	return minio.PutObjectOptions{
+		ContentType:        metadata.ContentType,
+		ContentEncoding:    metadata.ContentEncoding,
+		ContentDisposition: metadata.ContentDisposition,
+		UserMetadata:       metadata.UserMetadata,
+	}
+}
+
+func toStorageError(err error) error {
+	switch minio.ToErrorResponse(err).Code {
+	case errKeyNoSuchKey, errKeyNoSuchUpload:
+		return core.ErrNotFound
+	case errKeyInvalidRange:
+		return core.ErrInvalidRange
+	case errKeyInvalidPart, errKeyInvalidPartOrder, errKeyEntityTooSmall:
+		return core.ErrInvalidPart
+	case errKeyPrecondition:
+		return core.ErrPreconditionFailed
+	}
+	return err
+}
+
+// parseContentRangeSize returns the complete length from a "bytes start-end/size" header.
+func parseContentRangeSize(contentRange string) (int64, error) {
+	i := strings.LastIndex(contentRange, "/")
+	if i == -1 {
+		return 0, fmt.Errorf("invalid content range '%s'", contentRange)
+	}
+
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid content range '%s': %w", contentRange, err)
+	}
+	return size, nil
+}
@@ -1,7 +1,9 @@
 package minio
 
 import (
+	"bytes"
 	"context"
+	"io"
 	"testing"
 
 	"github.com/spacelift-io/homework-object-storage/internal/core"
@@ -9,28 +11,43 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func putBlob(storage *ObjectStorage, objectID string, blob []byte) error {
+	_, err := storage.Put(context.Background(), objectID, bytes.NewReader(blob), int64(len(blob)), core.ObjectMetadata{})
+	return err
+}
+
+func getBlob(storage *ObjectStorage, objectID string) ([]byte, error) {
+	body, _, err := storage.Get(context.Background(), objectID, core.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
 func TestObjectStorage(t *testing.T) {
-	storage, err := NewObjectStorage(context.Background(), testEnvironment.minioClient)
+	storage, err := NewObjectStorage(context.Background(), testEnvironment.minioClient, "default")
 	require.NoError(t, err)
 
 	t.Run("object should be put to storage", func(t *testing.T) {
 		const objectID = "object_1"
 
 		blob := []byte("blob")
-		err := storage.Put(context.Background(), objectID, blob)
+		err := putBlob(storage, objectID, blob)
 		require.NoError(t, err)
 
-		actualBlob, err := storage.Get(context.Background(), objectID)
+		actualBlob, err := getBlob(storage, objectID)
 		assert.NoError(t, err)
 
 		assert.Equal(t, blob, actualBlob)
 
 		t.Run("object with same ID, should override", func(t *testing.T) {
 			blob2 := []byte("blob_2")
-			err := storage.Put(context.Background(), objectID, blob2)
+			err := putBlob(storage, objectID, blob2)
 			require.NoError(t, err)
 
-			actualBlob, err := storage.Get(context.Background(), objectID)
+			actualBlob, err := getBlob(storage, objectID)
 			assert.NoError(t, err)
 
 			assert.Equal(t, blob2, actualBlob)
@@ -38,7 +55,169 @@ func TestObjectStorage(t *testing.T) {
 	})
 
 	t.Run("object does not exist, should return not found", func(t *testing.T) {
-		_, err := storage.Get(context.Background(), "random_object_key")
+		_, err := getBlob(storage, "random_object_key")
+		assert.Equal(t, core.ErrNotFound, err)
+	})
+
+	t.Run("object info should be returned", func(t *testing.T) {
+		const objectID = "object_3"
+
+		err := putBlob(storage, objectID, []byte("blob"))
+		require.NoError(t, err)
+
+		info, err := storage.Stat(context.Background(), objectID)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), info.Size)
+		assert.NotEmpty(t, info.ETag)
+
+		_, err = storage.Stat(context.Background(), "random_object_key")
+		assert.Equal(t, core.ErrNotFound, err)
+	})
+
+	t.Run("object of unknown size should be streamed to storage", func(t *testing.T) {
+		const objectID = "object_4"
+
+		blob := []byte("streamed blob")
+		stored, err := storage.Put(context.Background(), objectID, io.MultiReader(bytes.NewReader(blob)), -1, core.ObjectMetadata{})
+		require.NoError(t, err)
+
+		body, info, err := storage.Get(context.Background(), objectID, core.GetOptions{})
+		require.NoError(t, err)
+		defer body.Close()
+
+		actualBlob, err := io.ReadAll(body)
+		assert.NoError(t, err)
+		assert.Equal(t, blob, actualBlob)
+		assert.Equal(t, int64(len(blob)), info.Size)
+		assert.Equal(t, info.ETag, stored.ETag)
+		assert.Equal(t, info.Size, stored.Size)
+	})
+
+	t.Run("object metadata should be stored with the object", func(t *testing.T) {
+		const objectID = "object_6"
+
+		metadata := core.ObjectMetadata{
+			ContentType:        "application/json",
+			ContentEncoding:    "gzip",
+			ContentDisposition: `attachment; filename="blob.json"`,
+			UserMetadata:       map[string]string{"Owner": "team-a"},
+		}
+		blob := []byte("{}")
+		_, err := storage.Put(context.Background(), objectID, bytes.NewReader(blob), int64(len(blob)), metadata)
+		require.NoError(t, err)
+
+		info, err := storage.Stat(context.Background(), objectID)
+		require.NoError(t, err)
+		assert.Equal(t, metadata, info.Metadata)
+
+		body, info, err := storage.Get(context.Background(), objectID, core.GetOptions{})
+		require.NoError(t, err)
+		body.Close()
+		assert.Equal(t, metadata, info.Metadata)
+	})
+
+	t.Run("object should be assembled from multipart upload", func(t *testing.T) {
+		const objectID = "object_7"
+
+		uploadID, err := storage.InitiateMultipart(context.Background(), objectID, core.ObjectMetadata{ContentType: "text/plain"})
+		require.NoError(t, err)
+
+		// Every part but the last has to be at least 5 MiB.
+		first := bytes.Repeat([]byte("a"), 5<<20)
+		second := []byte("tail")
+		_, err = storage.PutPart(context.Background(), objectID, uploadID, 2, bytes.NewReader(second), int64(len(second)))
+		require.NoError(t, err)
+		_, err = storage.PutPart(context.Background(), objectID, uploadID, 1, bytes.NewReader(first), int64(len(first)))
+		require.NoError(t, err)
+
+		parts, err := storage.ListParts(context.Background(), objectID, uploadID)
+		require.NoError(t, err)
+		require.Len(t, parts, 2)
+		assert.Equal(t, 1, parts[0].PartNumber)
+
+		info, err := storage.CompleteMultipart(context.Background(), objectID, uploadID, parts)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(first)+len(second)), info.Size)
+		assert.Equal(t, "text/plain", info.Metadata.ContentType)
+
+		t.Run("completed upload is not found", func(t *testing.T) {
+			err := storage.AbortMultipart(context.Background(), objectID, uploadID)
+			assert.Equal(t, core.ErrNotFound, err)
+		})
+	})
+
+	t.Run("aborted multipart upload is not found", func(t *testing.T) {
+		const objectID = "object_8"
+
+		uploadID, err := storage.InitiateMultipart(context.Background(), objectID, core.ObjectMetadata{})
+		require.NoError(t, err)
+		require.NoError(t, storage.AbortMultipart(context.Background(), objectID, uploadID))
+
+		_, err = storage.ListParts(context.Background(), objectID, uploadID)
 		assert.Equal(t, core.ErrNotFound, err)
 	})
+
+	t.Run("object range should be returned with the full object size", func(t *testing.T) {
+		const objectID = "object_5"
+
+		require.NoError(t, putBlob(storage, objectID, []byte("0123456789")))
+
+		body, info, err := storage.Get(context.Background(), objectID, core.GetOptions{Range: &core.ByteRange{Start: 2, End: 5}})
+		require.NoError(t, err)
+		defer body.Close()
+
+		actualBlob, err := io.ReadAll(body)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("2345"), actualBlob)
+		assert.Equal(t, int64(10), info.Size)
+
+		t.Run("range beyond the object, should return invalid range", func(t *testing.T) {
+			_, _, err := storage.Get(context.Background(), objectID, core.GetOptions{Range: &core.ByteRange{Start: 20, End: 25}})
+			assert.Equal(t, core.ErrInvalidRange, err)
+		})
+
+		t.Run("when read is pinned to another ETag, should return precondition failed", func(t *testing.T) {
+			_, _, err := storage.Get(context.Background(), objectID, core.GetOptions{Range: &core.ByteRange{Start: 2, End: 5}, MatchETag: "other"})
+			assert.Equal(t, core.ErrPreconditionFailed, err)
+
+			body, _, err := storage.Get(context.Background(), objectID, core.GetOptions{MatchETag: info.ETag})
+			require.NoError(t, err)
+			body.Close()
+		})
+	})
+
+	t.Run("objects should be listed by prefix in order", func(t *testing.T) {
+		for _, objectID := range []string{"list_b", "list_a", "list_c", "other"} {
+			require.NoError(t, putBlob(storage, objectID, []byte(objectID)))
+		}
+
+		objects, err := storage.List(context.Background(), core.ListOptions{Prefix: "list_", Limit: 2})
+		require.NoError(t, err)
+		require.Len(t, objects, 2)
+		assert.Equal(t, "list_a", objects[0].ID)
+		assert.Equal(t, "list_b", objects[1].ID)
+
+		objects, err = storage.List(context.Background(), core.ListOptions{Prefix: "list_", StartAfter: "list_b"})
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		assert.Equal(t, "list_c", objects[0].ID)
+	})
+
+	t.Run("object should be deleted from storage", func(t *testing.T) {
+		const objectID = "object_2"
+
+		err := putBlob(storage, objectID, []byte("blob"))
+		require.NoError(t, err)
+
+		err = storage.Delete(context.Background(), objectID)
+		assert.NoError(t, err)
+
+		_, err = getBlob(storage, objectID)
+		assert.Equal(t, core.ErrNotFound, err)
+
+		t.Run("deleting missing object, should return not found", func(t *testing.T) {
+			err := storage.Delete(context.Background(), objectID)
+			assert.Equal(t, core.ErrNotFound, err)
+		})
+	})
 }
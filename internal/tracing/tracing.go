@@ -0,0 +1,116 @@
+// Package tracing sets up OpenTelemetry tracing and holds the span helpers shared by instrumented packages.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName identifies spans of this service, unless overridden by OTEL_SERVICE_NAME.
+const ServiceName = "homework-object-storage"
+
+const instrumentationName = "github.com/spacelift-io/homework-object-storage"
+
+// Exporters selectable by OTEL_TRACES_EXPORTER. The OTLP exporter is configured by the standard
+// OTEL_EXPORTER_OTLP_* variables, e.g. OTEL_EXPORTER_OTLP_ENDPOINT.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+const exporterEnv = "OTEL_TRACES_EXPORTER"
+
+const (
+	objectIDKey   = attribute.Key("object.id")
+	storageIDKey  = attribute.Key("storage.id")
+	storageIDsKey = attribute.Key("storage.ids")
+	addressKey    = attribute.Key("storage.address")
+)
+
+// Setup installs the W3C trace context propagator and a tracer provider exporting spans to the exporter
+// named by OTEL_TRACES_EXPORTER. Spans aren't recorded when it's unset. The returned function flushes
+// spans which haven't been exported yet.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name := os.Getenv(exporterEnv); name {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracegrpc.New(ctx)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown %s '%s'", exporterEnv, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating span exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("creating resource: %w", err)
+	}
+	// Attributes from the environment take precedence, so OTEL_SERVICE_NAME can rename the service.
+	res, err = resource.Merge(res, resource.Environment())
+	if err != nil {
+		return nil, fmt.Errorf("creating resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span which has to be finished by End.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End finishes the span, marking it failed unless err is nil or a missing object.
+func End(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, core.ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func ObjectID(objectID string) attribute.KeyValue {
+	return objectIDKey.String(objectID)
+}
+
+// StorageID names the storage an object was read from.
+func StorageID(storageID string) attribute.KeyValue {
+	return storageIDKey.String(storageID)
+}
+
+// StorageIDs names the storages selected for an object.
+func StorageIDs(storageIDs []string) attribute.KeyValue {
+	return storageIDsKey.StringSlice(storageIDs)
+}
+
+// Address names the storage node a request was sent to.
+func Address(address string) attribute.KeyValue {
+	return addressKey.String(address)
+}
@@ -0,0 +1,203 @@
+// Package tus serves resumable uploads over the tus protocol, version 1.0.0 with the creation
+// and termination extensions. See https://tus.io/protocols/resumable-upload.
+package tus
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+)
+
+const (
+	tusVersion        = "1.0.0"
+	tusExtensions     = "creation,termination"
+	offsetContentType = "application/offset+octet-stream"
+)
+
+// Router serves uploads created by POST to basePath at basePath/{uploadID}.
+func Router(objectDistributor *distributor.ObjectDistributor, basePath string) http.Handler {
+	s := &store{objectDistributor: objectDistributor, chunkSize: maxChunkSize}
+
+	r := mux.NewRouter()
+	r.HandleFunc(basePath, options).Methods(http.MethodOptions)
+	r.HandleFunc(basePath, createUpload(s, basePath)).Methods(http.MethodPost)
+	r.HandleFunc(basePath+"/{uploadID}", options).Methods(http.MethodOptions)
+	r.HandleFunc(basePath+"/{uploadID}", headUpload(s)).Methods(http.MethodHead)
+	r.HandleFunc(basePath+"/{uploadID}", patchUpload(s)).Methods(http.MethodPatch)
+	r.HandleFunc(basePath+"/{uploadID}", terminateUpload(s)).Methods(http.MethodDelete)
+	return requireResumable(r)
+}
+
+// requireResumable rejects requests of other protocol versions, only OPTIONS may go without one.
+func requireResumable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Tus-Resumable", tusVersion)
+		if r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func createUpload(s *store, basePath string) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Deferring the length is an extension which isn't supported.
+		length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		if err != nil || length < 0 {
+			http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+			return
+		}
+		metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		objectID := metadata[objectIDMetadataKey]
+		if !core.ValidObjectID(objectID) {
+			http.Error(w, "upload metadata has to carry a valid objectId", http.StatusBadRequest)
+			return
+		}
+
+		u, err := s.create(r.Context(), uploadInfo{
+			ObjectID:    objectID,
+			Length:      length,
+			Metadata:    r.Header.Get("Upload-Metadata"),
+			ContentType: metadata[fileTypeMetadataKey],
+		})
+		if err != nil {
+			writeError(w, "", err, "creating upload")
+			return
+		}
+
+		// Empty uploads are complete right away.
+		if u.complete() {
+			if err := u.assemble(r.Context(), s.objectDistributor); err != nil {
+				writeError(w, u.id, err, "assembling upload")
+				return
+			}
+		}
+
+		w.Header().Set("Location", basePath+"/"+u.id)
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func headUpload(s *store) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uploadID := mux.Vars(r)["uploadID"]
+		u, err := s.load(r.Context(), uploadID)
+		if err != nil {
+			writeError(w, uploadID, err, "loading upload")
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Upload-Offset", strconv.FormatInt(u.offset, 10))
+		w.Header().Set("Upload-Length", strconv.FormatInt(u.info.Length, 10))
+		if u.info.Metadata != "" {
+			w.Header().Set("Upload-Metadata", u.info.Metadata)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func patchUpload(s *store) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uploadID := mux.Vars(r)["uploadID"]
+		if r.Header.Get("Content-Type") != offsetContentType {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil || offset < 0 {
+			http.Error(w, "invalid Upload-Offset", http.StatusBadRequest)
+			return
+		}
+
+		u, err := s.load(r.Context(), uploadID)
+		if err != nil {
+			writeError(w, uploadID, err, "loading upload")
+			return
+		}
+		if offset != u.offset {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if r.ContentLength > u.info.Length-u.offset {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		// The last chunk may have been stored before assembling failed, then an empty PATCH retries it.
+		if !u.complete() && r.ContentLength != 0 {
+			if _, err := u.writeChunks(r.Context(), r.Body, s.chunkSize); err != nil {
+				writeError(w, uploadID, err, "writing upload chunk")
+				return
+			}
+		}
+		if u.complete() {
+			if err := u.assemble(r.Context(), s.objectDistributor); err != nil {
+				writeError(w, uploadID, err, "assembling upload")
+				return
+			}
+		}
+
+		w.Header().Set("Upload-Offset", strconv.FormatInt(u.offset, 10))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func terminateUpload(s *store) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		uploadID := mux.Vars(r)["uploadID"]
+		u, err := s.load(r.Context(), uploadID)
+		if err != nil {
+			writeError(w, uploadID, err, "loading upload")
+			return
+		}
+
+		if err := u.terminate(r.Context()); err != nil {
+			writeError(w, uploadID, err, "terminating upload")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeError(w http.ResponseWriter, uploadID string, err error, msg string) {
+	switch {
+	case err == core.ErrNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case err == errChunkTooLarge:
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	case errors.Is(err, core.ErrQuorumNotReached):
+		logrus.WithFields(logrus.Fields{
+			"uploadID": uploadID,
+		}).WithError(err).Error(msg)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	default:
+		logrus.WithFields(logrus.Fields{
+			"uploadID": uploadID,
+		}).WithError(err).Error(msg)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
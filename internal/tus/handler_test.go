@@ -0,0 +1,143 @@
+package tus
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *distributor.ObjectDistributor, []*memory.ObjectStorage) {
+	objectDistributor := distributor.NewObjectDistributor(util.NewConsistentHashStorageSelector(), distributor.WithReplication(2, 2))
+	storages := make([]*memory.ObjectStorage, 0)
+	for _, storageID := range []string{"a", "b"} {
+		storage := memory.NewObjectStorage()
+		storages = append(storages, storage)
+		objectDistributor.AddStorage(storageID, storage, 1)
+	}
+
+	server := httptest.NewServer(Router(objectDistributor, "/files"))
+	t.Cleanup(server.Close)
+	return server, objectDistributor, storages
+}
+
+func request(t *testing.T, method, url string, body []byte, headers map[string]string) *http.Response {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Tus-Resumable", tusVersion)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func createUploadRequest(t *testing.T, server *httptest.Server, objectID string, length string) string {
+	resp := request(t, http.MethodPost, server.URL+"/files", nil, map[string]string{
+		"Upload-Length":   length,
+		"Upload-Metadata": "objectId " + base64.StdEncoding.EncodeToString([]byte(objectID)) + ",filetype dGV4dC9wbGFpbg==",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	return server.URL + resp.Header.Get("Location")
+}
+
+func patch(t *testing.T, url string, offset string, chunk []byte) *http.Response {
+	return request(t, http.MethodPatch, url, chunk, map[string]string{
+		"Content-Type":  offsetContentType,
+		"Upload-Offset": offset,
+	})
+}
+
+func internalObjectCount(t *testing.T, storages []*memory.ObjectStorage) int {
+	count := 0
+	for _, storage := range storages {
+		objects, err := storage.List(context.TODO(), core.ListOptions{Prefix: core.InternalObjectPrefix})
+		require.NoError(t, err)
+		count += len(objects)
+	}
+	return count
+}
+
+func TestRouter(t *testing.T) {
+	t.Run("upload is resumed from its offset and assembled once complete", func(t *testing.T) {
+		server, objectDistributor, storages := newTestServer(t)
+		location := createUploadRequest(t, server, "object", "11")
+
+		resp := patch(t, location, "0", []byte("hello "))
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "6", resp.Header.Get("Upload-Offset"))
+
+		resp = request(t, http.MethodHead, location, nil, nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "6", resp.Header.Get("Upload-Offset"))
+		assert.Equal(t, "11", resp.Header.Get("Upload-Length"))
+
+		t.Run("when offset doesn't match, should conflict", func(t *testing.T) {
+			resp := patch(t, location, "0", []byte("hello "))
+			assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		})
+
+		t.Run("when chunk exceeds length, should be rejected", func(t *testing.T) {
+			resp := patch(t, location, "6", []byte("world and more"))
+			assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+		})
+
+		resp = patch(t, location, "6", []byte("world"))
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, "11", resp.Header.Get("Upload-Offset"))
+
+		body, info, err := objectDistributor.GetObject(context.TODO(), "object", distributor.GetOptions{})
+		require.NoError(t, err)
+		defer body.Close()
+		blob, err := io.ReadAll(body)
+		require.NoError(t, err)
+		assert.Equal(t, "hello world", string(blob))
+		assert.Equal(t, "text/plain", info.Metadata.ContentType)
+
+		assert.Zero(t, internalObjectCount(t, storages))
+		resp = request(t, http.MethodHead, location, nil, nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("terminated upload is removed", func(t *testing.T) {
+		server, _, storages := newTestServer(t)
+		location := createUploadRequest(t, server, "object", "10")
+		require.Equal(t, http.StatusNoContent, patch(t, location, "0", []byte("hello")).StatusCode)
+
+		resp := request(t, http.MethodDelete, location, nil, nil)
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Zero(t, internalObjectCount(t, storages))
+
+		resp = patch(t, location, "5", []byte("world"))
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("when object ID is missing, upload is not created", func(t *testing.T) {
+		server, _, _ := newTestServer(t)
+		resp := request(t, http.MethodPost, server.URL+"/files", nil, map[string]string{"Upload-Length": "10"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("when protocol version is missing, should fail precondition", func(t *testing.T) {
+		server, _, _ := newTestServer(t)
+		resp, err := http.Post(server.URL+"/files", "", strings.NewReader(""))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusPreconditionFailed, resp.StatusCode)
+		assert.Equal(t, tusVersion, resp.Header.Get("Tus-Version"))
+	})
+}
@@ -0,0 +1,38 @@
+package tus
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const (
+	// objectIDMetadataKey names the object the upload becomes once it's complete.
+	objectIDMetadataKey = "objectId"
+	// fileTypeMetadataKey is the key tus clients conventionally send the MIME type under.
+	fileTypeMetadataKey = "filetype"
+)
+
+var errInvalidMetadata = errors.New("invalid upload metadata")
+
+// parseMetadata decodes an Upload-Metadata header, comma separated keys each followed by an optional base64 value.
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		if key == "" {
+			return nil, errInvalidMetadata
+		}
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, errInvalidMetadata
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
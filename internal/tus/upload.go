@@ -0,0 +1,274 @@
+package tus
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/core"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+)
+
+// Upload state lives on the storage owning the target object when the upload is created:
+// an info object plus chunk objects of at most maxChunkSize each, named by the offset the chunk starts at.
+// Any gateway can so resume the upload by reading the state back from that storage.
+const (
+	uploadsPrefix = core.InternalObjectPrefix + "tus/"
+	infoName      = "info"
+	chunksPrefix  = "chunks/"
+	maxChunkSize  = 8 << 20
+)
+
+// uploadIDSeparator joins the storage ID and the upload token, storage IDs never contain it.
+const uploadIDSeparator = "/"
+
+var errChunkTooLarge = errors.New("chunk exceeds upload length")
+
+type uploadInfo struct {
+	ObjectID string `json:"objectId"`
+	Length   int64  `json:"length"`
+	// Metadata is the Upload-Metadata header the upload was created with.
+	Metadata    string `json:"metadata,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+}
+
+type upload struct {
+	id      string
+	token   string
+	storage distributor.ObjectStorage
+	info    uploadInfo
+	// chunks are the contiguous chunks from the start of the upload.
+	chunks []string
+	offset int64
+}
+
+type store struct {
+	objectDistributor *distributor.ObjectDistributor
+	// chunkSize bounds the chunks a PATCH body is stored in, each is buffered in memory.
+	chunkSize int64
+}
+
+func (s *store) create(ctx context.Context, info uploadInfo) (*upload, error) {
+	storageID, storage, err := s.objectDistributor.PrimaryStorage(info.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	u := &upload{
+		token:   hex.EncodeToString(token),
+		storage: storage,
+		info:    info,
+	}
+	u.id = base64.RawURLEncoding.EncodeToString([]byte(storageID + uploadIDSeparator + u.token))
+
+	blob, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := storage.Put(ctx, u.key(infoName), bytes.NewReader(blob), int64(len(blob)), core.ObjectMetadata{}); err != nil {
+		return nil, fmt.Errorf("storing upload info: %w", err)
+	}
+	return u, nil
+}
+
+// load reads the upload state back from its storage, unknown uploads are not found.
+func (s *store) load(ctx context.Context, uploadID string) (*upload, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(uploadID)
+	if err != nil {
+		return nil, core.ErrNotFound
+	}
+	storageID, token, ok := strings.Cut(string(decoded), uploadIDSeparator)
+	if !ok || token == "" {
+		return nil, core.ErrNotFound
+	}
+	storage, ok := s.objectDistributor.Storage(storageID)
+	if !ok {
+		return nil, core.ErrNotFound
+	}
+
+	u := &upload{
+		id:      uploadID,
+		token:   token,
+		storage: storage,
+	}
+	body, _, err := storage.Get(ctx, u.key(infoName), core.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if err := json.NewDecoder(body).Decode(&u.info); err != nil {
+		return nil, fmt.Errorf("decoding upload info: %w", err)
+	}
+
+	chunks, err := storage.List(ctx, core.ListOptions{Prefix: u.key(chunksPrefix)})
+	if err != nil {
+		return nil, fmt.Errorf("listing upload chunks: %w", err)
+	}
+	// Chunks are listed by their offset, anything after a gap is left over from a conflicting write.
+	for _, chunk := range chunks {
+		offset, err := strconv.ParseInt(strings.TrimPrefix(chunk.ID, u.key(chunksPrefix)), 10, 64)
+		if err != nil || offset != u.offset {
+			break
+		}
+		u.chunks = append(u.chunks, chunk.ID)
+		u.offset += chunk.Size
+	}
+	return u, nil
+}
+
+// writeChunks stores the body in chunks of at most chunkSize as it streams in, advancing the offset
+// as each is stored. When the body breaks off, the bytes received until then are kept.
+func (u *upload) writeChunks(ctx context.Context, r io.Reader, chunkSize int64) (int64, error) {
+	body := &chunkReader{r: r, remaining: u.info.Length - u.offset}
+	// One byte past the rest of the upload is enough to notice a body which is too large.
+	if chunkSize > body.remaining+1 {
+		chunkSize = body.remaining + 1
+	}
+	buf := make([]byte, chunkSize)
+	for {
+		n, readErr := readChunk(body, buf)
+		if body.err != nil {
+			return u.offset, body.err
+		}
+		if n > 0 {
+			key := u.key(fmt.Sprintf("%s%020d", chunksPrefix, u.offset))
+			if _, err := u.storage.Put(ctx, key, bytes.NewReader(buf[:n]), int64(n), core.ObjectMetadata{}); err != nil {
+				return u.offset, err
+			}
+			u.chunks = append(u.chunks, key)
+			u.offset += int64(n)
+		}
+		if readErr == io.EOF {
+			return u.offset, nil
+		}
+		if readErr != nil {
+			return u.offset, readErr
+		}
+	}
+}
+
+// readChunk fills buf unless r ends or fails first, the error is io.EOF only when r ended cleanly.
+func readChunk(r io.Reader, buf []byte) (int, error) {
+	read := 0
+	for read < len(buf) {
+		n, err := r.Read(buf[read:])
+		read += n
+		if err != nil {
+			return read, err
+		}
+	}
+	return read, nil
+}
+
+func (u *upload) complete() bool {
+	return u.offset == u.info.Length
+}
+
+// assemble streams the chunks into the target object and removes the upload state.
+func (u *upload) assemble(ctx context.Context, objectDistributor *distributor.ObjectDistributor) error {
+	body := &chunksReader{ctx: ctx, storage: u.storage, chunks: u.chunks}
+	defer body.Close()
+
+	_, err := objectDistributor.PutObject(ctx, u.info.ObjectID, body, u.info.Length, distributor.PutOptions{
+		Metadata: core.ObjectMetadata{ContentType: u.info.ContentType},
+	})
+	if err != nil {
+		return fmt.Errorf("putting assembled object: %w", err)
+	}
+	return u.terminate(ctx)
+}
+
+// terminate removes the upload state, the info object goes last so a failed termination can be retried.
+func (u *upload) terminate(ctx context.Context) error {
+	for _, chunk := range u.chunks {
+		if err := u.storage.Delete(ctx, chunk); err != nil && err != core.ErrNotFound {
+			return err
+		}
+	}
+	if err := u.storage.Delete(ctx, u.key(infoName)); err != nil && err != core.ErrNotFound {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"uploadID": u.id,
+		"id":       u.info.ObjectID,
+	}).Debug("removed upload state")
+	return nil
+}
+
+func (u *upload) key(name string) string {
+	return uploadsPrefix + u.token + "/" + name
+}
+
+// chunkReader counts the bytes read and fails once the chunk goes past the upload length.
+type chunkReader struct {
+	r         io.Reader
+	remaining int64
+	read      int64
+	err       error
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if c.read > c.remaining {
+		c.err = errChunkTooLarge
+		return n, c.err
+	}
+	return n, err
+}
+
+// chunksReader reads the chunks one after another, opening each only once the previous one is drained.
+type chunksReader struct {
+	ctx     context.Context
+	storage distributor.ObjectStorage
+	chunks  []string
+	current io.ReadCloser
+}
+
+func (c *chunksReader) Read(p []byte) (int, error) {
+	for {
+		if c.current == nil {
+			if len(c.chunks) == 0 {
+				return 0, io.EOF
+			}
+			body, _, err := c.storage.Get(c.ctx, c.chunks[0], core.GetOptions{})
+			if err != nil {
+				return 0, fmt.Errorf("getting chunk '%s': %w", c.chunks[0], err)
+			}
+			c.current = body
+			c.chunks = c.chunks[1:]
+		}
+
+		n, err := c.current.Read(p)
+		if err == io.EOF {
+			c.current.Close()
+			c.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (c *chunksReader) Close() error {
+	if c.current == nil {
+		return nil
+	}
+	return c.current.Close()
+}
@@ -0,0 +1,58 @@
+package tus
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/storage/memory"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errBodyBroken = errors.New("body broken")
+
+type brokenReader struct{}
+
+func (brokenReader) Read(p []byte) (int, error) {
+	return 0, errBodyBroken
+}
+
+func TestUploadWriteChunks(t *testing.T) {
+	objectDistributor := distributor.NewObjectDistributor(util.NewConsistentHashStorageSelector())
+	objectDistributor.AddStorage("a", memory.NewObjectStorage(), 1)
+	s := &store{objectDistributor: objectDistributor, chunkSize: 4}
+
+	u, err := s.create(context.TODO(), uploadInfo{ObjectID: "object", Length: 20})
+	require.NoError(t, err)
+
+	t.Run("when body breaks off, the bytes received are kept", func(t *testing.T) {
+		offset, err := u.writeChunks(context.TODO(), io.MultiReader(strings.NewReader("0123456789"), brokenReader{}), s.chunkSize)
+		assert.Equal(t, errBodyBroken, err)
+		assert.Equal(t, int64(10), offset)
+
+		loaded, err := s.load(context.TODO(), u.id)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), loaded.offset)
+		assert.Len(t, loaded.chunks, 3)
+
+		t.Run("when body exceeds the upload, only chunks within its length are kept", func(t *testing.T) {
+			offset, err := loaded.writeChunks(context.TODO(), strings.NewReader("0123456789!"), s.chunkSize)
+			assert.Equal(t, errChunkTooLarge, err)
+			assert.Equal(t, int64(18), offset)
+
+			loaded, err := s.load(context.TODO(), u.id)
+			require.NoError(t, err)
+			assert.Equal(t, int64(18), loaded.offset)
+
+			offset, err = loaded.writeChunks(context.TODO(), strings.NewReader("89"), s.chunkSize)
+			require.NoError(t, err)
+			assert.Equal(t, int64(20), offset)
+			assert.True(t, loaded.complete())
+		})
+	})
+}
@@ -1,44 +1,225 @@
 package util
 
 import (
+	"fmt"
 	"hash/fnv"
+	"math"
 
 	"github.com/buraksezer/consistent"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
 )
 
+// ConsistentHashStorageSelector places storages on a consistent hash ring. Weights are honoured
+// by giving a storage one ring member per unit of weight, rounded to the nearest whole number.
 type ConsistentHashStorageSelector struct {
 	consistent *consistent.Consistent
+	config     consistent.Config
+	version    uint64
+	// weights of storages, members are derived from them.
+	weights map[string]float64
+	// zones of storages placed in one, owners of a partition are spread across them.
+	zones map[string]string
+	// previous is the ring from before the last membership change, objects may still live on its owners.
+	previous distributor.Ring
 }
 
-func NewConsistentHashStorageSelector() *ConsistentHashStorageSelector {
+// Default ring parameters, taken from the consistent hash library example.
+const (
+	DefaultPartitionCount    = 7
+	DefaultReplicationFactor = 20
+	DefaultLoad              = 1.25
+)
+
+type ConsistentHashOption func(config *consistent.Config)
+
+// WithRingParameters overrides the number of partitions, the points every member is hashed to
+// and the maximum partitions of a member relative to the average.
+func WithRingParameters(partitionCount, replicationFactor int, load float64) ConsistentHashOption {
+	return func(config *consistent.Config) {
+		config.PartitionCount = partitionCount
+		config.ReplicationFactor = replicationFactor
+		config.Load = load
+	}
+}
+
+func NewConsistentHashStorageSelector(opts ...ConsistentHashOption) *ConsistentHashStorageSelector {
+	config := consistent.Config{
+		Hasher:            fnvHasher{},
+		PartitionCount:    DefaultPartitionCount,
+		ReplicationFactor: DefaultReplicationFactor,
+		Load:              DefaultLoad,
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
 	return &ConsistentHashStorageSelector{
-		consistent: consistent.New(nil, consistent.Config{
-			Hasher: fnvHasher{},
-			// Default configuration from library example
-			PartitionCount:    7,
-			ReplicationFactor: 20,
-			Load:              1.25,
-		}),
+		consistent: consistent.New(nil, config),
+		config:     config,
+		weights:    make(map[string]float64),
+		zones:      make(map[string]string),
 	}
 }
 
-func (c *ConsistentHashStorageSelector) AddStorage(storageID string) {
-	c.consistent.Add(memberID(storageID))
+func (c *ConsistentHashStorageSelector) AddStorage(storageID string, weight float64) {
+	c.AddZonedStorage(storageID, weight, "")
+}
+
+func (c *ConsistentHashStorageSelector) AddZonedStorage(storageID string, weight float64, zone string) {
+	c.previous = c.Snapshot()
+	c.version++
+	if _, ok := c.weights[storageID]; ok {
+		c.removeMembers(storageID)
+	}
+
+	c.weights[storageID] = weight
+	setZone(c.zones, storageID, zone)
+	for _, member := range storageMembers(storageID, weight) {
+		c.consistent.Add(member)
+	}
 }
 
 func (c *ConsistentHashStorageSelector) RemoveStorage(storageID string) {
-	c.consistent.Remove(storageID)
+	c.previous = c.Snapshot()
+	c.version++
+	c.removeMembers(storageID)
+	delete(c.weights, storageID)
+	delete(c.zones, storageID)
+}
+
+func (c *ConsistentHashStorageSelector) removeMembers(storageID string) {
+	for _, member := range storageMembers(storageID, c.weights[storageID]) {
+		c.consistent.Remove(member.String())
+	}
 }
 
 func (c *ConsistentHashStorageSelector) LocateStorage(objectID string) string {
-	storageID := c.consistent.LocateKey([]byte(objectID))
-	return storageID.String()
+	storageIDs := c.LocateStorages(objectID, 1)
+	if len(storageIDs) == 0 {
+		return ""
+	}
+	return storageIDs[0]
+}
+
+func (c *ConsistentHashStorageSelector) LocateStorages(objectID string, count int) []string {
+	return c.PartitionOwners(c.LocatePartition(objectID), count)
 }
 
-type memberID string
+// Reconfigure rebuilds the ring with changed parameters. Objects may move, so it counts as a membership change.
+func (c *ConsistentHashStorageSelector) Reconfigure(opts ...ConsistentHashOption) {
+	c.previous = c.Snapshot()
+	c.version++
+	for _, opt := range opts {
+		opt(&c.config)
+	}
+
+	members := c.consistent.GetMembers()
+	if len(members) == 0 {
+		members = nil
+	}
+	c.consistent = consistent.New(members, c.config)
+}
+
+// Snapshot returns a copy of the ring, the ring is fully determined by its members and configuration.
+func (c *ConsistentHashStorageSelector) Snapshot() distributor.Ring {
+	weights := make(map[string]float64, len(c.weights))
+	for storageID, weight := range c.weights {
+		weights[storageID] = weight
+	}
+	zones := make(map[string]string, len(c.zones))
+	for storageID, zone := range c.zones {
+		zones[storageID] = zone
+	}
+
+	// The library can't distribute partitions among zero members, an empty ring has to be created without them.
+	members := c.consistent.GetMembers()
+	if len(members) == 0 {
+		members = nil
+	}
+
+	return &ConsistentHashStorageSelector{
+		consistent: consistent.New(members, c.config),
+		config:     c.config,
+		version:    c.version,
+		weights:    weights,
+		zones:      zones,
+	}
+}
+
+func (c *ConsistentHashStorageSelector) Previous() distributor.Ring {
+	return c.previous
+}
+
+func (c *ConsistentHashStorageSelector) Version() uint64 {
+	return c.version
+}
+
+func (c *ConsistentHashStorageSelector) PartitionCount() int {
+	return c.config.PartitionCount
+}
+
+func (c *ConsistentHashStorageSelector) LocatePartition(objectID string) int {
+	return c.consistent.FindPartitionID([]byte(objectID))
+}
+
+func (c *ConsistentHashStorageSelector) PartitionOwners(partitionID, count int) []string {
+	if count > len(c.weights) {
+		count = len(c.weights)
+	}
+	if count < 1 {
+		return nil
+	}
+
+	// Members of a single storage can be neighbours on the ring, so all of them are walked until enough distinct storages are found.
+	members, err := c.consistent.GetClosestNForPartition(partitionID, len(c.consistent.GetMembers()))
+	if err != nil {
+		return nil
+	}
+
+	storageIDs := make([]string, 0, len(c.weights))
+	for _, member := range members {
+		storageID := member.(memberID).storageID
+		if contains(storageIDs, storageID) {
+			continue
+		}
+		storageIDs = append(storageIDs, storageID)
+		// Without zones the closest storages are the owners, otherwise every storage is a candidate.
+		if len(c.zones) == 0 && len(storageIDs) == count {
+			break
+		}
+	}
+	return spreadZones(storageIDs, c.zones, count)
+}
+
+// storageMembers returns ring members of the storage, the first one is named after the storage itself.
+func storageMembers(storageID string, weight float64) []memberID {
+	count := int(math.Round(weight))
+	if count < 1 {
+		count = 1
+	}
+
+	members := []memberID{{name: storageID, storageID: storageID}}
+	for i := 1; i < count; i++ {
+		members = append(members, memberID{name: fmt.Sprintf("%s#%d", storageID, i), storageID: storageID})
+	}
+	return members
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type memberID struct {
+	name      string
+	storageID string
+}
 
 func (id memberID) String() string {
-	return string(id)
+	return id.name
 }
 
 type fnvHasher struct{}
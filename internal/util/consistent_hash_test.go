@@ -0,0 +1,98 @@
+package util
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsistentHashStorageSelector(t *testing.T) {
+	t.Run("when empty, no storage is located", func(t *testing.T) {
+		selector := NewConsistentHashStorageSelector()
+
+		assert.Equal(t, "", selector.LocateStorage("object_id"))
+		assert.Empty(t, selector.LocateStorages("object_id", 2))
+	})
+
+	t.Run("when every storage is removed, no storage is located", func(t *testing.T) {
+		selector := NewConsistentHashStorageSelector()
+		selector.AddStorage("storage_id", 2)
+		selector.RemoveStorage("storage_id")
+
+		assert.Equal(t, "", selector.LocateStorage("object_id"))
+		assert.Empty(t, selector.LocateStorages("object_id", 2))
+		assert.Empty(t, selector.Snapshot().LocateStorages("object_id", 2))
+	})
+
+	t.Run("when ring parameters are given, partitions follow them", func(t *testing.T) {
+		selector := NewConsistentHashStorageSelector(WithRingParameters(31, 10, 1.5))
+		selector.AddStorage("1", 1)
+		selector.AddStorage("2", 1)
+
+		assert.Equal(t, 31, selector.PartitionCount())
+		assert.Equal(t, 31, selector.Snapshot().(distributor.PartitionedRing).PartitionCount())
+		for i := 0; i < 50; i++ {
+			assert.Less(t, selector.LocatePartition(fmt.Sprintf("object_%d", i)), 31)
+		}
+	})
+
+	t.Run("when reconfigured, members are kept and the previous ring is remembered", func(t *testing.T) {
+		selector := NewConsistentHashStorageSelector()
+		selector.AddStorage("1", 1)
+		selector.AddStorage("2", 2)
+		before := selector.Snapshot()
+
+		selector.Reconfigure(WithRingParameters(31, 10, 1.5))
+
+		assert.Equal(t, 31, selector.PartitionCount())
+		assert.Equal(t, before.Version()+1, selector.Version())
+		assert.Equal(t, before, selector.Previous())
+		assert.ElementsMatch(t, []string{"1", "2"}, selector.LocateStorages("object_id", 3))
+	})
+
+	t.Run("when weighted, owners are distinct and led by the primary owner", func(t *testing.T) {
+		selector := NewConsistentHashStorageSelector()
+		selector.AddStorage("1", 1)
+		selector.AddStorage("2", 3)
+		selector.AddStorage("3", 1)
+
+		for i := 0; i < 50; i++ {
+			objectID := fmt.Sprintf("object_%d", i)
+			owners := selector.LocateStorages(objectID, 5)
+
+			assert.ElementsMatch(t, []string{"1", "2", "3"}, owners)
+			assert.Equal(t, selector.LocateStorage(objectID), owners[0])
+		}
+	})
+
+	t.Run("when storage is removed, previous ring still locates objects on it", func(t *testing.T) {
+		selector := NewConsistentHashStorageSelector()
+		selector.AddStorage("1", 1)
+		selector.AddStorage("2", 1)
+		snapshot := selector.Snapshot()
+
+		selector.RemoveStorage("2")
+
+		assert.Equal(t, uint64(3), selector.Version())
+		assert.Equal(t, snapshot.Version(), selector.Previous().Version())
+		for i := 0; i < 50; i++ {
+			objectID := fmt.Sprintf("object_%d", i)
+			assert.Equal(t, snapshot.LocateStorages(objectID, 2), selector.Previous().LocateStorages(objectID, 2))
+			assert.Equal(t, []string{"1"}, selector.LocateStorages(objectID, 2))
+		}
+	})
+
+	t.Run("partitions are owned by the same storages as their objects", func(t *testing.T) {
+		selector := NewConsistentHashStorageSelector()
+		selector.AddStorage("1", 1)
+		selector.AddStorage("2", 1)
+
+		var ring distributor.PartitionedRing = selector
+		for i := 0; i < 50; i++ {
+			objectID := fmt.Sprintf("object_%d", i)
+			assert.Equal(t, ring.LocateStorages(objectID, 2), ring.PartitionOwners(ring.LocatePartition(objectID), 2))
+		}
+	})
+}
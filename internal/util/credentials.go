@@ -0,0 +1,111 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+var ErrNoCredentials = errors.New("no credentials found")
+
+// dockerSecretsDir is where Docker mounts secrets referenced only by their name.
+const dockerSecretsDir = "/run/secrets"
+
+type Credentials struct {
+	AccessKey string
+	SecretKey string
+}
+
+// CredentialResolver finds credentials of the Minio node running in the container.
+type CredentialResolver interface {
+	Resolve(ctx context.Context, c Container, node StorageNode) (Credentials, error)
+}
+
+// CredentialEnv names a pair of environment variables holding credentials.
+type CredentialEnv struct {
+	AccessKey string
+	SecretKey string
+}
+
+func (e CredentialEnv) String() string {
+	return fmt.Sprintf("%s/%s", e.AccessKey, e.SecretKey)
+}
+
+// DefaultCredentialEnvs are tried in order, current Minio images use the root user variables.
+var DefaultCredentialEnvs = []CredentialEnv{
+	{AccessKey: "MINIO_ROOT_USER", SecretKey: "MINIO_ROOT_PASSWORD"},
+	{AccessKey: "MINIO_ACCESS_KEY", SecretKey: "MINIO_SECRET_KEY"},
+}
+
+type ContainerFileReadFn func(ctx context.Context, containerID, path string) ([]byte, error)
+
+// EnvCredentialResolver reads credentials from container environment variables. Every variable
+// may also be given as a *_FILE variable pointing at a file inside the container, like a Docker secret.
+type EnvCredentialResolver struct {
+	envs       []CredentialEnv
+	readFileFn ContainerFileReadFn
+}
+
+// NewEnvCredentialResolver creates a resolver trying envs in order. Variables named by the
+// node's labels always go first. Without readFileFn, *_FILE variables are ignored.
+func NewEnvCredentialResolver(readFileFn ContainerFileReadFn, envs ...CredentialEnv) *EnvCredentialResolver {
+	return &EnvCredentialResolver{
+		envs:       envs,
+		readFileFn: readFileFn,
+	}
+}
+
+func (r *EnvCredentialResolver) Resolve(ctx context.Context, c Container, node StorageNode) (Credentials, error) {
+	envs := r.envs
+	if node.AccessKeyEnv != "" && node.SecretKeyEnv != "" {
+		envs = append([]CredentialEnv{{AccessKey: node.AccessKeyEnv, SecretKey: node.SecretKeyEnv}}, envs...)
+	}
+
+	tried := make([]string, 0, len(envs))
+	for _, env := range envs {
+		accessKey, accessKeyFound, err := r.lookup(ctx, c, env.AccessKey)
+		if err != nil {
+			return Credentials{}, err
+		}
+		secretKey, secretKeyFound, err := r.lookup(ctx, c, env.SecretKey)
+		if err != nil {
+			return Credentials{}, err
+		}
+
+		switch {
+		case accessKeyFound && secretKeyFound:
+			return Credentials{AccessKey: accessKey, SecretKey: secretKey}, nil
+		case accessKeyFound:
+			return Credentials{}, fmt.Errorf("%w in '%s' container: %s is set but %s is missing", ErrNoCredentials, c.Name, env.AccessKey, env.SecretKey)
+		case secretKeyFound:
+			return Credentials{}, fmt.Errorf("%w in '%s' container: %s is set but %s is missing", ErrNoCredentials, c.Name, env.SecretKey, env.AccessKey)
+		}
+		tried = append(tried, env.String())
+	}
+	return Credentials{}, fmt.Errorf("%w in '%s' container, tried %s and their *_FILE variants", ErrNoCredentials, c.Name, strings.Join(tried, ", "))
+}
+
+// lookup returns the value of the variable, or the content of the file its *_FILE variant points at.
+func (r *EnvCredentialResolver) lookup(ctx context.Context, c Container, name string) (string, bool, error) {
+	if value, ok := c.Environment[name]; ok && value != "" {
+		return value, true, nil
+	}
+
+	filePath, ok := c.Environment[name+"_FILE"]
+	if !ok || filePath == "" || r.readFileFn == nil {
+		return "", false, nil
+	}
+	if !path.IsAbs(filePath) {
+		filePath = path.Join(dockerSecretsDir, filePath)
+	}
+
+	content, err := r.readFileFn(ctx, c.ID, filePath)
+	if err != nil {
+		return "", false, fmt.Errorf("reading %s_FILE '%s' from '%s' container: %w", name, filePath, c.Name, err)
+	}
+
+	value := strings.TrimSpace(string(content))
+	return value, value != "", nil
+}
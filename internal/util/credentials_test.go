@@ -0,0 +1,89 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvCredentialResolver(t *testing.T) {
+	files := map[string]string{
+		"/run/secrets/access": "file_access\n",
+		"/secrets/secret":     "file_secret",
+	}
+	readFileFn := func(ctx context.Context, containerID, path string) ([]byte, error) {
+		content, ok := files[path]
+		if !ok {
+			return nil, errors.New("no such file")
+		}
+		return []byte(content), nil
+	}
+	resolver := NewEnvCredentialResolver(readFileFn, DefaultCredentialEnvs...)
+
+	resolve := func(env map[string]string, node StorageNode) (Credentials, error) {
+		return resolver.Resolve(context.Background(), Container{Name: "/node", Environment: env}, node)
+	}
+
+	t.Run("root user variables are preferred over access keys", func(t *testing.T) {
+		creds, err := resolve(map[string]string{
+			"MINIO_ROOT_USER":     "root",
+			"MINIO_ROOT_PASSWORD": "password",
+			"MINIO_ACCESS_KEY":    "access",
+			"MINIO_SECRET_KEY":    "secret",
+		}, StorageNode{})
+		require.NoError(t, err)
+		assert.Equal(t, Credentials{AccessKey: "root", SecretKey: "password"}, creds)
+	})
+
+	t.Run("access keys are used when root user is missing", func(t *testing.T) {
+		creds, err := resolve(map[string]string{
+			"MINIO_ACCESS_KEY": "access",
+			"MINIO_SECRET_KEY": "secret",
+		}, StorageNode{})
+		require.NoError(t, err)
+		assert.Equal(t, Credentials{AccessKey: "access", SecretKey: "secret"}, creds)
+	})
+
+	t.Run("variables named by labels go first", func(t *testing.T) {
+		creds, err := resolve(map[string]string{
+			"MINIO_ACCESS_KEY": "access",
+			"MINIO_SECRET_KEY": "secret",
+			"CUSTOM_ACCESS":    "custom_access",
+			"CUSTOM_SECRET":    "custom_secret",
+		}, StorageNode{AccessKeyEnv: "CUSTOM_ACCESS", SecretKeyEnv: "CUSTOM_SECRET"})
+		require.NoError(t, err)
+		assert.Equal(t, Credentials{AccessKey: "custom_access", SecretKey: "custom_secret"}, creds)
+	})
+
+	t.Run("file variants are read from the container", func(t *testing.T) {
+		creds, err := resolve(map[string]string{
+			"MINIO_ROOT_USER_FILE":     "access",
+			"MINIO_ROOT_PASSWORD_FILE": "/secrets/secret",
+		}, StorageNode{})
+		require.NoError(t, err)
+		assert.Equal(t, Credentials{AccessKey: "file_access", SecretKey: "file_secret"}, creds)
+	})
+
+	t.Run("when file can't be read, we should return an error", func(t *testing.T) {
+		_, err := resolve(map[string]string{
+			"MINIO_ROOT_USER_FILE":     "missing",
+			"MINIO_ROOT_PASSWORD_FILE": "/secrets/secret",
+		}, StorageNode{})
+		assert.ErrorContains(t, err, "/run/secrets/missing")
+	})
+
+	t.Run("when only half of the pair is set, we should return an error", func(t *testing.T) {
+		_, err := resolve(map[string]string{"MINIO_ROOT_USER": "root"}, StorageNode{})
+		assert.ErrorIs(t, err, ErrNoCredentials)
+		assert.ErrorContains(t, err, "MINIO_ROOT_PASSWORD is missing")
+	})
+
+	t.Run("when no credentials are set, we should return an error", func(t *testing.T) {
+		_, err := resolve(map[string]string{}, StorageNode{})
+		assert.ErrorIs(t, err, ErrNoCredentials)
+		assert.ErrorContains(t, err, "MINIO_ROOT_USER/MINIO_ROOT_PASSWORD, MINIO_ACCESS_KEY/MINIO_SECRET_KEY")
+	})
+}
@@ -0,0 +1,159 @@
+package util
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+)
+
+// RendezvousStorageSelector places objects by weighted rendezvous (highest random weight) hashing.
+// Every storage scores every object and the highest scores own it, so adding or removing a storage
+// only moves objects to or from that storage. Unlike the ring, placement isn't partitioned, which
+// spreads objects evenly with any number of storages at the cost of scoring all of them per lookup.
+type RendezvousStorageSelector struct {
+	version uint64
+	// storages are kept sorted by ID, so equal scores are broken the same way on every lookup.
+	storages []rendezvousStorage
+	// zones of storages placed in one, owners of an object are spread across them.
+	zones map[string]string
+	// previous is the placement from before the last membership change, objects may still live on its owners.
+	previous distributor.Ring
+}
+
+type rendezvousStorage struct {
+	id     string
+	hash   uint64
+	weight float64
+}
+
+func NewRendezvousStorageSelector() *RendezvousStorageSelector {
+	return &RendezvousStorageSelector{zones: make(map[string]string)}
+}
+
+func (r *RendezvousStorageSelector) AddStorage(storageID string, weight float64) {
+	r.AddZonedStorage(storageID, weight, "")
+}
+
+func (r *RendezvousStorageSelector) AddZonedStorage(storageID string, weight float64, zone string) {
+	r.previous = r.Snapshot()
+	r.version++
+	if weight <= 0 {
+		weight = defaultWeight
+	}
+	setZone(r.zones, storageID, zone)
+
+	i := r.find(storageID)
+	if i < len(r.storages) && r.storages[i].id == storageID {
+		r.storages[i].weight = weight
+		return
+	}
+
+	storage := rendezvousStorage{id: storageID, hash: hashString(storageID), weight: weight}
+	r.storages = append(r.storages, rendezvousStorage{})
+	copy(r.storages[i+1:], r.storages[i:])
+	r.storages[i] = storage
+}
+
+func (r *RendezvousStorageSelector) RemoveStorage(storageID string) {
+	r.previous = r.Snapshot()
+	r.version++
+	delete(r.zones, storageID)
+
+	if i := r.find(storageID); i < len(r.storages) && r.storages[i].id == storageID {
+		r.storages = append(r.storages[:i], r.storages[i+1:]...)
+	}
+}
+
+// find returns the index of the storage, or where it would be inserted.
+func (r *RendezvousStorageSelector) find(storageID string) int {
+	return sort.Search(len(r.storages), func(i int) bool {
+		return r.storages[i].id >= storageID
+	})
+}
+
+func (r *RendezvousStorageSelector) LocateStorage(objectID string) string {
+	storageIDs := r.LocateStorages(objectID, 1)
+	if len(storageIDs) == 0 {
+		return ""
+	}
+	return storageIDs[0]
+}
+
+func (r *RendezvousStorageSelector) LocateStorages(objectID string, count int) []string {
+	if count > len(r.storages) {
+		count = len(r.storages)
+	}
+	if count < 1 {
+		return nil
+	}
+
+	objectHash := hashString(objectID)
+	type scored struct {
+		storageID string
+		score     float64
+	}
+	scores := make([]scored, len(r.storages))
+	for i, storage := range r.storages {
+		scores[i] = scored{storageID: storage.id, score: score(objectHash, storage)}
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].score > scores[j].score
+	})
+
+	storageIDs := make([]string, len(scores))
+	for i := range storageIDs {
+		storageIDs[i] = scores[i].storageID
+	}
+	return spreadZones(storageIDs, r.zones, count)
+}
+
+// Snapshot returns a copy of the placement, it's fully determined by the storages and their weights.
+func (r *RendezvousStorageSelector) Snapshot() distributor.Ring {
+	storages := make([]rendezvousStorage, len(r.storages))
+	copy(storages, r.storages)
+	zones := make(map[string]string, len(r.zones))
+	for storageID, zone := range r.zones {
+		zones[storageID] = zone
+	}
+
+	return &RendezvousStorageSelector{
+		version:  r.version,
+		storages: storages,
+		zones:    zones,
+	}
+}
+
+func (r *RendezvousStorageSelector) Previous() distributor.Ring {
+	return r.previous
+}
+
+func (r *RendezvousStorageSelector) Version() uint64 {
+	return r.version
+}
+
+// score is the storage's weighted claim on the object. With u uniform in (0, 1), -weight/ln(u)
+// makes the chance of having the highest score proportional to the weight.
+func score(objectHash uint64, storage rendezvousStorage) float64 {
+	h := mix64(objectHash ^ storage.hash)
+	// The top 53 bits fill a float64 mantissa, the half keeps u away from both 0 and 1.
+	u := (float64(h>>11) + 0.5) / (1 << 53)
+	return -storage.weight / math.Log(u)
+}
+
+func hashString(s string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(s))
+	return hash.Sum64()
+}
+
+// mix64 is the splitmix64 finalizer, FNV alone spreads similar inputs poorly across the high bits.
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
@@ -0,0 +1,182 @@
+package util
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/stretchr/testify/assert"
+)
+
+const comparedObjects = 20000
+
+func TestRendezvousStorageSelector(t *testing.T) {
+	t.Run("when empty, no storage is located", func(t *testing.T) {
+		selector := NewRendezvousStorageSelector()
+
+		assert.Equal(t, "", selector.LocateStorage("object_id"))
+		assert.Empty(t, selector.LocateStorages("object_id", 2))
+	})
+
+	t.Run("when every storage is removed, no storage is located", func(t *testing.T) {
+		selector := NewRendezvousStorageSelector()
+		selector.AddStorage("storage_id", 2)
+		selector.RemoveStorage("storage_id")
+
+		assert.Equal(t, "", selector.LocateStorage("object_id"))
+		assert.Empty(t, selector.Snapshot().LocateStorages("object_id", 2))
+	})
+
+	t.Run("when weighted, owners are distinct and led by the primary owner", func(t *testing.T) {
+		selector := NewRendezvousStorageSelector()
+		selector.AddStorage("1", 1)
+		selector.AddStorage("2", 3)
+		selector.AddStorage("3", 1)
+
+		for i := 0; i < 50; i++ {
+			objectID := fmt.Sprintf("object_%d", i)
+			owners := selector.LocateStorages(objectID, 5)
+
+			assert.ElementsMatch(t, []string{"1", "2", "3"}, owners)
+			assert.Equal(t, selector.LocateStorage(objectID), owners[0])
+			assert.Equal(t, owners[:2], selector.LocateStorages(objectID, 2))
+		}
+	})
+
+	t.Run("when weighted, primary objects follow the weights", func(t *testing.T) {
+		selector := NewRendezvousStorageSelector()
+		selector.AddStorage("1", 1)
+		selector.AddStorage("2", 2)
+		selector.AddStorage("3", 1)
+
+		counts := primaryCounts(selector, comparedObjects)
+		assert.InDelta(t, comparedObjects/4, counts["1"], comparedObjects*0.02)
+		assert.InDelta(t, comparedObjects/2, counts["2"], comparedObjects*0.02)
+		assert.InDelta(t, comparedObjects/4, counts["3"], comparedObjects*0.02)
+	})
+
+	t.Run("when storages change, the previous placement is remembered", func(t *testing.T) {
+		selector := NewRendezvousStorageSelector()
+		selector.AddStorage("1", 1)
+		before := selector.Snapshot()
+
+		selector.AddStorage("2", 1)
+		assert.Equal(t, before.Version()+1, selector.Version())
+		assert.Equal(t, before.LocateStorages("object_id", 2), selector.Previous().LocateStorages("object_id", 2))
+
+		t.Run("when weight is updated, the storage isn't duplicated", func(t *testing.T) {
+			selector.AddStorage("2", 5)
+			assert.Len(t, selector.LocateStorages("object_id", 5), 2)
+		})
+	})
+
+	t.Run("when storage joins, objects only move to it", func(t *testing.T) {
+		selector := NewRendezvousStorageSelector()
+		for i := 0; i < 4; i++ {
+			selector.AddStorage(fmt.Sprint(i), 1)
+		}
+		before := selector.Snapshot()
+		selector.AddStorage("new", 1)
+
+		for i := 0; i < 1000; i++ {
+			objectID := fmt.Sprintf("object_%d", i)
+			previousOwners := before.LocateStorages(objectID, 2)
+			for _, ownerID := range selector.LocateStorages(objectID, 2) {
+				assert.True(t, ownerID == "new" || contains(previousOwners, ownerID), objectID)
+			}
+		}
+	})
+}
+
+// TestSelectorComparison compares the rendezvous selector against consistent hash rings for
+// typical node counts, run it with -v to see the numbers.
+func TestSelectorComparison(t *testing.T) {
+	type compared struct {
+		name        string
+		newSelector func() distributor.StorageSelector
+		// maxNodes is how many nodes the selector can place, zero means unlimited.
+		maxNodes int
+	}
+	selectors := []compared{
+		{
+			name:        "rendezvous",
+			newSelector: func() distributor.StorageSelector { return NewRendezvousStorageSelector() },
+		},
+		{
+			name:        "consistent hash",
+			newSelector: func() distributor.StorageSelector { return NewConsistentHashStorageSelector() },
+			// The library can't distribute partitions among more members than there are partitions.
+			maxNodes: DefaultPartitionCount,
+		},
+		{
+			name: "consistent hash, 271 partitions",
+			newSelector: func() distributor.StorageSelector {
+				return NewConsistentHashStorageSelector(WithRingParameters(271, DefaultReplicationFactor, DefaultLoad))
+			},
+		},
+	}
+
+	for _, nodes := range []int{3, 5, 8, 12} {
+		t.Run(fmt.Sprintf("with %d nodes", nodes), func(t *testing.T) {
+			imbalance := make(map[string]float64)
+			moved := make(map[string]float64)
+			for _, c := range selectors {
+				if c.maxNodes > 0 && nodes+1 > c.maxNodes {
+					t.Logf("%s: can't place %d nodes", c.name, nodes+1)
+					continue
+				}
+
+				selector := c.newSelector()
+				for i := 0; i < nodes; i++ {
+					selector.AddStorage(fmt.Sprintf("10.0.0.%d", i+2), 1)
+				}
+				imbalance[c.name] = maxLoad(primaryCounts(selector, comparedObjects), nodes)
+
+				before := selector.Snapshot()
+				selector.AddStorage(fmt.Sprintf("10.0.0.%d", nodes+2), 1)
+				moved[c.name] = movedFraction(before, selector, comparedObjects)
+
+				t.Logf("%s: busiest node has %.2fx the average, adding a node moves %.1f%% of objects (ideal %.1f%%)",
+					c.name, imbalance[c.name], moved[c.name]*100, 100/float64(nodes+1))
+			}
+
+			// Every node owns a share close to the average, and about the share of the new node moves.
+			assert.Less(t, imbalance["rendezvous"], 1.05)
+			assert.InDelta(t, 1/float64(nodes+1), moved["rendezvous"], 0.02)
+			for name, ringImbalance := range imbalance {
+				assert.LessOrEqual(t, imbalance["rendezvous"], ringImbalance, name)
+			}
+		})
+	}
+}
+
+func primaryCounts(ring distributor.Ring, objects int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < objects; i++ {
+		counts[ring.LocateStorages(fmt.Sprintf("object_%d", i), 1)[0]]++
+	}
+	return counts
+}
+
+// maxLoad returns the objects of the busiest node relative to the average.
+func maxLoad(counts map[string]int, nodes int) float64 {
+	busiest, total := 0, 0
+	for _, count := range counts {
+		total += count
+		if count > busiest {
+			busiest = count
+		}
+	}
+	return float64(busiest) / (float64(total) / float64(nodes))
+}
+
+func movedFraction(before, after distributor.Ring, objects int) float64 {
+	moved := 0
+	for i := 0; i < objects; i++ {
+		objectID := fmt.Sprintf("object_%d", i)
+		if before.LocateStorages(objectID, 1)[0] != after.LocateStorages(objectID, 1)[0] {
+			moved++
+		}
+	}
+	return float64(moved) / float64(objects)
+}
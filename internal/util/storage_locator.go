@@ -3,94 +3,353 @@ package util
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/metrics"
 	minioStorage "github.com/spacelift-io/homework-object-storage/internal/storage/minio"
 )
 
 type MinioStorageLocator struct {
-	containerSearchFn ContainerSearchFn
-	onStorageAdded    func(storageID string, storage *minioStorage.ObjectStorage)
-	onStorageRemoved  func(storageID string)
+	containerSearchFn  ContainerSearchFn
+	credentialResolver CredentialResolver
+	onStorageAdded     OnStorageAdded
+	onStorageRemoved   OnStorageRemoved
+	nodeDefaults       NodeDefaults
 
-	storageCache map[string]*minioStorage.ObjectStorage
+	// membership orders ring changes with their callbacks, it is taken before l.
+	membership sync.Mutex
+	l          sync.Mutex
+	// healthCheckInterval is passed to the Minio clients of storages.
+	healthCheckInterval time.Duration
+	storageCache        map[string]*minioStorage.ObjectStorage
+	nodes               map[string]StorageNode
+	// containerStorages maps container IDs to storage IDs, so stopped containers can be removed by their ID.
+	containerStorages map[string]string
+	// lastChecks holds when nodes in the ring last passed a health check, or were added.
+	lastChecks map[string]time.Time
+	// failedNodes were removed from the ring for failing a health check, they are reported
+	// until they are added again or their container disappears.
+	failedNodes map[string]NodeStatus
+}
+
+// NodeStatus is the health of a storage node as seen by the last check.
+type NodeStatus struct {
+	Node      StorageNode
+	Healthy   bool
+	LastCheck time.Time
 }
 
 type Container struct {
+	ID          string
 	Name        string
 	IP          string
 	Environment map[string]string
+	Labels      map[string]string
+}
+
+type ContainerEventType int
+
+const (
+	ContainerStarted ContainerEventType = iota
+	ContainerStopped
+)
+
+type ContainerEvent struct {
+	Type ContainerEventType
+	// Container is fully populated for started containers, stopped containers carry only ID and name.
+	Container Container
 }
 
-type OnStorageAdded func(storageID string, storage *minioStorage.ObjectStorage)
+type OnStorageAdded func(node StorageNode, storage *minioStorage.ObjectStorage)
 
 type OnStorageRemoved func(storageID string)
 
 type ContainerSearchFn func(ctx context.Context) ([]Container, error)
 
-func NewMinioStorageLocator(containerSearchFn ContainerSearchFn, onAddedFn OnStorageAdded, onRemovedFn OnStorageRemoved) *MinioStorageLocator {
-	return &MinioStorageLocator{
-		containerSearchFn: containerSearchFn,
-		storageCache:      make(map[string]*minioStorage.ObjectStorage),
-		onStorageAdded:    onAddedFn,
-		onStorageRemoved:  onRemovedFn,
+type LocatorOption func(l *MinioStorageLocator)
+
+// WithNodeDefaults sets the port and bucket of nodes whose containers don't have labels overriding them.
+func WithNodeDefaults(defaults NodeDefaults) LocatorOption {
+	return func(l *MinioStorageLocator) {
+		l.nodeDefaults = defaults
 	}
 }
 
-func (l *MinioStorageLocator) Tick(ctx context.Context) error {
-	if err := l.CheckCurrentNodes(); err != nil {
-		return err
+// WithHealthCheckInterval sets the interval of health checks done by the Minio clients of nodes.
+func WithHealthCheckInterval(interval time.Duration) LocatorOption {
+	return func(l *MinioStorageLocator) {
+		l.healthCheckInterval = interval
 	}
+}
+
+func NewMinioStorageLocator(containerSearchFn ContainerSearchFn, credentialResolver CredentialResolver, onAddedFn OnStorageAdded, onRemovedFn OnStorageRemoved, opts ...LocatorOption) *MinioStorageLocator {
+	l := &MinioStorageLocator{
+		containerSearchFn:   containerSearchFn,
+		credentialResolver:  credentialResolver,
+		storageCache:        make(map[string]*minioStorage.ObjectStorage),
+		nodes:               make(map[string]StorageNode),
+		containerStorages:   make(map[string]string),
+		lastChecks:          make(map[string]time.Time),
+		failedNodes:         make(map[string]NodeStatus),
+		onStorageAdded:      onAddedFn,
+		onStorageRemoved:    onRemovedFn,
+		nodeDefaults:        DefaultNodeDefaults,
+		healthCheckInterval: minioStorage.DefaultHealthCheckInterval,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// SetHealthCheckInterval changes the health check interval of current and future storages.
+func (l *MinioStorageLocator) SetHealthCheckInterval(interval time.Duration) {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	l.healthCheckInterval = interval
+	for _, storage := range l.storageCache {
+		storage.SetHealthCheckInterval(interval)
+	}
+}
+
+func (l *MinioStorageLocator) Tick(ctx context.Context) error {
+	l.CheckCurrentNodes()
 	return l.CheckForNewStorages(ctx)
 }
 
-func (l *MinioStorageLocator) CheckCurrentNodes() error {
+// CheckCurrentNodes removes nodes failing their health check. Nodes whose check couldn't run are left as they are.
+func (l *MinioStorageLocator) CheckCurrentNodes() {
+	// Health checks take a while, so they don't block events handled meanwhile.
+	l.l.Lock()
+	storages := make(map[string]*minioStorage.ObjectStorage, len(l.storageCache))
 	for storageID, storage := range l.storageCache {
+		storages[storageID] = storage
+	}
+	l.l.Unlock()
+
+	for storageID, storage := range storages {
 		online, err := storage.Online()
 		if err != nil {
-			return err
+			logrus.WithFields(logrus.Fields{
+				"storage": storageID,
+			}).WithError(err).Error("checking storage health")
+			continue
 		}
 
-		if !online {
-			delete(l.storageCache, storageID)
-
-			if l.onStorageRemoved != nil {
-				l.onStorageRemoved(storageID)
+		if online {
+			l.l.Lock()
+			if _, ok := l.lastChecks[storageID]; ok {
+				l.lastChecks[storageID] = time.Now()
 			}
+			l.l.Unlock()
+		} else {
+			l.removeUnhealthyStorage(storageID)
 		}
+		// Set after the removal, so nodes dropped for failing the check stay reported as unhealthy.
+		metrics.SetStorageHealthy(storageID, online)
 	}
-	return nil
 }
 
+// CheckForNewStorages adds storages of newly found containers and removes storages
+// whose containers are gone, which covers events missed by HandleContainerEvent.
 func (l *MinioStorageLocator) CheckForNewStorages(ctx context.Context) error {
 	containers, err := l.containerSearchFn(ctx)
 	if err != nil {
 		return err
 	}
 
+	found := make(map[string]bool, len(containers))
 	for _, c := range containers {
-		if _, ok := l.storageCache[c.IP]; ok {
-			continue
+		found[c.ID] = true
+		// A misconfigured or unreachable container doesn't keep the others out of the ring.
+		if err := l.AddContainer(ctx, c); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"container": c.Name,
+			}).WithError(err).Error("adding storage")
 		}
+	}
 
-		minioClient, err := minio.New(fmt.Sprintf("%s:9000", c.IP), &minio.Options{
-			Creds:  credentials.NewStaticV4(c.Environment["MINIO_ACCESS_KEY"], c.Environment["MINIO_SECRET_KEY"], ""),
-			Secure: false,
-		})
-		if err != nil {
-			return fmt.Errorf("creating minio storage for '%s': %w", c.IP, err)
+	l.l.Lock()
+	vanished := make([]string, 0)
+	for containerID := range l.containerStorages {
+		if !found[containerID] {
+			vanished = append(vanished, containerID)
 		}
-
-		objStorage, err := minioStorage.NewObjectStorage(ctx, minioClient)
-		if err != nil {
-			return fmt.Errorf("creating minio object storage: %w", err)
+	}
+	for storageID, status := range l.failedNodes {
+		if !found[status.Node.ContainerID] {
+			delete(l.failedNodes, storageID)
 		}
+	}
+	l.l.Unlock()
 
-		l.storageCache[c.IP] = objStorage
-		if l.onStorageAdded != nil {
-			l.onStorageAdded(c.IP, objStorage)
-		}
+	for _, containerID := range vanished {
+		l.RemoveContainer(containerID)
+	}
+	return nil
+}
+
+// HandleContainerEvent applies a container lifecycle event right away, without waiting for the next tick.
+func (l *MinioStorageLocator) HandleContainerEvent(ctx context.Context, event ContainerEvent) error {
+	switch event.Type {
+	case ContainerStarted:
+		return l.AddContainer(ctx, event.Container)
+	case ContainerStopped:
+		l.RemoveContainer(event.Container.ID)
+	}
+	return nil
+}
+
+// AddContainer creates a storage for the container unless it is already known.
+func (l *MinioStorageLocator) AddContainer(ctx context.Context, c Container) error {
+	if c.IP == "" {
+		return nil
+	}
+
+	if l.recordKnownContainer(c) {
+		return nil
+	}
+
+	// The node is reached without holding the lock, so health reports and other containers aren't held up.
+	l.l.Lock()
+	healthCheckInterval := l.healthCheckInterval
+	l.l.Unlock()
+
+	node, err := newStorageNode(c, l.nodeDefaults)
+	if err != nil {
+		return fmt.Errorf("configuring storage for '%s' container: %w", c.Name, err)
+	}
+
+	creds, err := l.credentialResolver.Resolve(ctx, c, node)
+	if err != nil {
+		return fmt.Errorf("resolving credentials: %w", err)
+	}
+
+	minioClient, err := minio.New(node.Address, &minio.Options{
+		Creds:  credentials.NewStaticV4(creds.AccessKey, creds.SecretKey, ""),
+		Secure: node.Secure,
+	})
+	if err != nil {
+		return fmt.Errorf("creating minio storage for '%s': %w", c.IP, err)
+	}
+
+	objStorage, err := minioStorage.NewObjectStorage(ctx, minioClient, node.Bucket, minioStorage.WithHealthCheckInterval(healthCheckInterval))
+	if err != nil {
+		return fmt.Errorf("creating minio object storage: %w", err)
+	}
+
+	l.membership.Lock()
+	defer l.membership.Unlock()
+
+	// The container may have been added concurrently, by an event racing a resync.
+	if l.recordKnownContainer(c) {
+		return nil
+	}
+
+	l.l.Lock()
+	objStorage.SetHealthCheckInterval(l.healthCheckInterval)
+	l.storageCache[node.ID] = objStorage
+	l.nodes[node.ID] = node
+	l.containerStorages[c.ID] = node.ID
+	// Creating the storage reached the node, which counts as its first health check.
+	l.lastChecks[node.ID] = time.Now()
+	delete(l.failedNodes, node.ID)
+	metrics.SetRingMembers(len(l.storageCache))
+	metrics.SetStorageHealthy(node.ID, true)
+	l.l.Unlock()
+
+	if l.onStorageAdded != nil {
+		l.onStorageAdded(node, objStorage)
 	}
 	return nil
 }
+
+// recordKnownContainer maps the container to its storage if the storage is already in the ring.
+func (l *MinioStorageLocator) recordKnownContainer(c Container) bool {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	if _, ok := l.storageCache[c.IP]; !ok {
+		return false
+	}
+	l.containerStorages[c.ID] = c.IP
+	return true
+}
+
+// RemoveContainer removes the storage of the container, unknown containers are ignored.
+func (l *MinioStorageLocator) RemoveContainer(containerID string) {
+	l.l.Lock()
+	storageID, ok := l.containerStorages[containerID]
+	l.l.Unlock()
+
+	if ok {
+		l.removeStorage(storageID)
+	}
+}
+
+func (l *MinioStorageLocator) removeStorage(storageID string) {
+	l.membership.Lock()
+	defer l.membership.Unlock()
+
+	l.l.Lock()
+	if _, ok := l.storageCache[storageID]; !ok {
+		l.l.Unlock()
+		return
+	}
+
+	delete(l.storageCache, storageID)
+	delete(l.nodes, storageID)
+	delete(l.lastChecks, storageID)
+	for containerID, id := range l.containerStorages {
+		if id == storageID {
+			delete(l.containerStorages, containerID)
+		}
+	}
+	metrics.SetRingMembers(len(l.storageCache))
+	metrics.ForgetStorage(storageID)
+	l.l.Unlock()
+
+	if l.onStorageRemoved != nil {
+		l.onStorageRemoved(storageID)
+	}
+}
+
+// removeUnhealthyStorage removes the storage and keeps reporting it as unhealthy.
+func (l *MinioStorageLocator) removeUnhealthyStorage(storageID string) {
+	l.l.Lock()
+	node, ok := l.nodes[storageID]
+	l.l.Unlock()
+	if !ok {
+		return
+	}
+
+	l.removeStorage(storageID)
+
+	l.l.Lock()
+	l.failedNodes[storageID] = NodeStatus{Node: node, Healthy: false, LastCheck: time.Now()}
+	l.l.Unlock()
+}
+
+// Nodes returns the status of nodes in the ring and of nodes removed for failing a health check, ordered by ID.
+func (l *MinioStorageLocator) Nodes() []NodeStatus {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	statuses := make([]NodeStatus, 0, len(l.nodes)+len(l.failedNodes))
+	for storageID, node := range l.nodes {
+		statuses = append(statuses, NodeStatus{Node: node, Healthy: true, LastCheck: l.lastChecks[storageID]})
+	}
+	for _, status := range l.failedNodes {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Node.ID < statuses[j].Node.ID
+	})
+	return statuses
+}
@@ -0,0 +1,48 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type credentialResolverFunc func(ctx context.Context, c Container, node StorageNode) (Credentials, error)
+
+func (f credentialResolverFunc) Resolve(ctx context.Context, c Container, node StorageNode) (Credentials, error) {
+	return f(ctx, c, node)
+}
+
+func TestMinioStorageLocator(t *testing.T) {
+	t.Run("when containers can't be added, the others are still checked", func(t *testing.T) {
+		containers := []Container{
+			{ID: "1", Name: "/bad_port", IP: "10.0.0.1", Labels: map[string]string{LabelPort: "port"}},
+			{ID: "2", Name: "/no_credentials", IP: "10.0.0.2"},
+		}
+		var resolved []string
+		var locator *MinioStorageLocator
+		resolver := credentialResolverFunc(func(ctx context.Context, c Container, node StorageNode) (Credentials, error) {
+			// Would deadlock if the locator were locked while resolving credentials.
+			locator.Nodes()
+			resolved = append(resolved, c.Name)
+			return Credentials{}, errors.New("no credentials")
+		})
+		locator = NewMinioStorageLocator(func(ctx context.Context) ([]Container, error) {
+			return containers, nil
+		}, resolver, nil, nil)
+
+		require.NoError(t, locator.CheckForNewStorages(context.Background()))
+		assert.Equal(t, []string{"/no_credentials"}, resolved)
+		assert.Empty(t, locator.Nodes())
+	})
+
+	t.Run("when containers can't be listed, should return the error", func(t *testing.T) {
+		locator := NewMinioStorageLocator(func(ctx context.Context) ([]Container, error) {
+			return nil, errors.New("docker unavailable")
+		}, credentialResolverFunc(nil), nil, nil)
+
+		assert.Error(t, locator.Tick(context.Background()))
+	})
+}
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Container labels overriding how the Minio node inside the container is reached and placed.
+const (
+	labelPrefix       = "object-storage."
+	LabelPort         = labelPrefix + "port"
+	LabelTLS          = labelPrefix + "tls"
+	LabelBucket       = labelPrefix + "bucket"
+	LabelWeight       = labelPrefix + "weight"
+	LabelZone         = labelPrefix + "zone"
+	LabelAccessKeyEnv = labelPrefix + "access-key-env"
+	LabelSecretKeyEnv = labelPrefix + "secret-key-env"
+)
+
+const defaultWeight = 1
+
+// NodeDefaults apply to storage nodes whose containers don't override them by labels.
+type NodeDefaults struct {
+	Port   int
+	Bucket string
+}
+
+var DefaultNodeDefaults = NodeDefaults{
+	Port:   9000,
+	Bucket: "default",
+}
+
+// StorageNode describes a discovered Minio node.
+type StorageNode struct {
+	ID            string
+	ContainerID   string
+	ContainerName string
+	Address       string
+	Secure        bool
+	Bucket        string
+	Weight        float64
+	Zone          string
+	// AccessKeyEnv and SecretKeyEnv name variables holding credentials, they are tried before the defaults.
+	AccessKeyEnv string
+	SecretKeyEnv string
+}
+
+// newStorageNode applies container label overrides on top of the defaults.
+func newStorageNode(c Container, defaults NodeDefaults) (StorageNode, error) {
+	node := StorageNode{
+		ID:            c.IP,
+		ContainerID:   c.ID,
+		ContainerName: c.Name,
+		Bucket:        defaults.Bucket,
+		Weight:        defaultWeight,
+		Zone:          c.Labels[LabelZone],
+		AccessKeyEnv:  c.Labels[LabelAccessKeyEnv],
+		SecretKeyEnv:  c.Labels[LabelSecretKeyEnv],
+	}
+
+	port := defaults.Port
+	if value, ok := c.Labels[LabelPort]; ok {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 65535 {
+			return StorageNode{}, fmt.Errorf("invalid '%s' label '%s'", LabelPort, value)
+		}
+		port = parsed
+	}
+	node.Address = fmt.Sprintf("%s:%d", c.IP, port)
+
+	if value, ok := c.Labels[LabelTLS]; ok {
+		secure, err := strconv.ParseBool(value)
+		if err != nil {
+			return StorageNode{}, fmt.Errorf("invalid '%s' label '%s'", LabelTLS, value)
+		}
+		node.Secure = secure
+	}
+
+	if value, ok := c.Labels[LabelWeight]; ok {
+		weight, err := strconv.ParseFloat(value, 64)
+		if err != nil || weight <= 0 {
+			return StorageNode{}, fmt.Errorf("invalid '%s' label '%s'", LabelWeight, value)
+		}
+		node.Weight = weight
+	}
+
+	if value := c.Labels[LabelBucket]; value != "" {
+		node.Bucket = value
+	}
+	return node, nil
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStorageNode(t *testing.T) {
+	t.Run("when container has no labels, defaults are used", func(t *testing.T) {
+		node, err := newStorageNode(Container{ID: "container_id", Name: "/node", IP: "10.0.0.2"}, DefaultNodeDefaults)
+		require.NoError(t, err)
+
+		assert.Equal(t, StorageNode{
+			ID:            "10.0.0.2",
+			ContainerID:   "container_id",
+			ContainerName: "/node",
+			Address:       "10.0.0.2:9000",
+			Bucket:        "default",
+			Weight:        1,
+		}, node)
+	})
+
+	t.Run("when container has labels, they override defaults", func(t *testing.T) {
+		node, err := newStorageNode(Container{IP: "10.0.0.2", Labels: map[string]string{
+			LabelPort:         "9443",
+			LabelTLS:          "true",
+			LabelBucket:       "objects",
+			LabelWeight:       "2.5",
+			LabelZone:         "eu-1",
+			LabelAccessKeyEnv: "ACCESS",
+			LabelSecretKeyEnv: "SECRET",
+		}}, DefaultNodeDefaults)
+		require.NoError(t, err)
+
+		assert.Equal(t, "10.0.0.2:9443", node.Address)
+		assert.True(t, node.Secure)
+		assert.Equal(t, "objects", node.Bucket)
+		assert.Equal(t, 2.5, node.Weight)
+		assert.Equal(t, "eu-1", node.Zone)
+		assert.Equal(t, "ACCESS", node.AccessKeyEnv)
+		assert.Equal(t, "SECRET", node.SecretKeyEnv)
+	})
+
+	t.Run("when label is invalid, we should return an error", func(t *testing.T) {
+		for label, value := range map[string]string{
+			LabelPort:   "port",
+			LabelTLS:    "maybe",
+			LabelWeight: "-1",
+		} {
+			_, err := newStorageNode(Container{IP: "10.0.0.2", Labels: map[string]string{label: value}}, DefaultNodeDefaults)
+			assert.Error(t, err, label)
+		}
+	})
+}
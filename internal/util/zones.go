@@ -0,0 +1,36 @@
+package util
+
+// spreadZones picks count of the storages, which are in order of preference. The most preferred storage
+// of every zone goes first, so replicas survive the loss of a zone. When there are fewer zones than
+// count, the rest are the most preferred storages left.
+func spreadZones(storageIDs []string, zones map[string]string, count int) []string {
+	if count > len(storageIDs) {
+		count = len(storageIDs)
+	}
+	if len(zones) == 0 {
+		return storageIDs[:count]
+	}
+
+	picked := make([]string, 0, count)
+	rest := make([]string, 0, len(storageIDs))
+	seen := make(map[string]bool)
+	for _, storageID := range storageIDs {
+		zone := zones[storageID]
+		if seen[zone] || len(picked) == count {
+			rest = append(rest, storageID)
+			continue
+		}
+		seen[zone] = true
+		picked = append(picked, storageID)
+	}
+	return append(picked, rest[:count-len(picked)]...)
+}
+
+// setZone records the zone of the storage, storages without one aren't recorded.
+func setZone(zones map[string]string, storageID, zone string) {
+	if zone == "" {
+		delete(zones, storageID)
+		return
+	}
+	zones[storageID] = zone
+}
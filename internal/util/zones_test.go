@@ -0,0 +1,59 @@
+package util
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpreadZones(t *testing.T) {
+	zones := map[string]string{"a": "1", "b": "1", "c": "2", "d": "2"}
+
+	assert.Equal(t, []string{"a", "c"}, spreadZones([]string{"a", "b", "c", "d"}, zones, 2))
+	assert.Equal(t, []string{"a", "c", "b"}, spreadZones([]string{"a", "b", "c", "d"}, zones, 3))
+	assert.Equal(t, []string{"b", "a"}, spreadZones([]string{"b", "a"}, zones, 5))
+
+	t.Run("storages without a zone share one", func(t *testing.T) {
+		assert.Equal(t, []string{"e", "a", "f"}, spreadZones([]string{"e", "f", "a"}, zones, 3))
+	})
+
+	t.Run("without zones, the most preferred storages are picked", func(t *testing.T) {
+		assert.Equal(t, []string{"b", "a"}, spreadZones([]string{"b", "a", "c"}, nil, 2))
+	})
+}
+
+func TestZonedStorageSelectors(t *testing.T) {
+	selectors := map[string]func() distributor.ZonedStorageSelector{
+		"consistent hash": func() distributor.ZonedStorageSelector { return NewConsistentHashStorageSelector() },
+		"rendezvous":      func() distributor.ZonedStorageSelector { return NewRendezvousStorageSelector() },
+	}
+	for name, newSelector := range selectors {
+		t.Run(fmt.Sprintf("when %s storages are in zones, owners are spread across them", name), func(t *testing.T) {
+			zoned, plain := newSelector(), newSelector()
+			for _, zone := range []string{"x", "y", "z"} {
+				for i := 0; i < 2; i++ {
+					storageID := fmt.Sprintf("%s%d", zone, i)
+					zoned.AddZonedStorage(storageID, 1, zone)
+					plain.AddStorage(storageID, 1)
+				}
+			}
+
+			for i := 0; i < 100; i++ {
+				objectID := fmt.Sprintf("object_%d", i)
+				owners := zoned.LocateStorages(objectID, 3)
+				if !assert.Len(t, owners, 3) {
+					continue
+				}
+				assert.Equal(t, plain.LocateStorage(objectID), owners[0], "zones don't change the primary owner")
+
+				zones := map[byte]bool{}
+				for _, owner := range owners {
+					zones[owner[0]] = true
+				}
+				assert.Len(t, zones, 3, objectID)
+			}
+		})
+	}
+}
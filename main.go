@@ -1,8 +1,13 @@
+//go:generate buf generate
+
 package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"flag"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,33 +18,72 @@ import (
 	"github.com/docker/docker/client"
 	gorillaHandlers "github.com/gorilla/handlers"
 	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/admin"
 	"github.com/spacelift-io/homework-object-storage/internal/client/docker"
+	"github.com/spacelift-io/homework-object-storage/internal/config"
 	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
 	"github.com/spacelift-io/homework-object-storage/internal/handler"
+	"github.com/spacelift-io/homework-object-storage/internal/metrics"
+	"github.com/spacelift-io/homework-object-storage/internal/rpc"
+	"github.com/spacelift-io/homework-object-storage/internal/s3"
 	minioStorage "github.com/spacelift-io/homework-object-storage/internal/storage/minio"
+	"github.com/spacelift-io/homework-object-storage/internal/tracing"
+	"github.com/spacelift-io/homework-object-storage/internal/tus"
 	"github.com/spacelift-io/homework-object-storage/internal/util"
+	"google.golang.org/grpc"
 )
 
-const minioDockerStorageName = "amazin-object-storage-node"
+// metricsPath serves Prometheus metrics on the main HTTP server.
+const metricsPath = "/metrics"
+
+// run starts the gateway, loadFn reads the config again when SIGHUP is received.
+func run(cfg config.Config, loadFn func() (config.Config, error)) error {
+	level, err := logrus.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return err
+	}
+	logrus.SetLevel(level)
+
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logrus.WithError(err).Error("shutting down tracing")
+		}
+	}()
 
-func run() error {
 	cli, err := client.NewClientWithOpts(client.FromEnv)
 	if err != nil {
 		return err
 	}
 
 	dockerClient := docker.NewClient(cli)
+	live := newLiveConfig(cfg)
 
-	objectDistributor := distributor.NewObjectDistributor(util.NewConsistentHashStorageSelector())
+	storageSelector := newStorageSelector(cfg.Ring)
+	objectDistributor := distributor.NewObjectDistributor(
+		storageSelector,
+		distributor.WithReplication(cfg.Replication.Replicas, cfg.Replication.WriteQuorum),
+		distributor.WithFallbackMigration(),
+	)
+	rebalancer := distributor.NewRebalancer(objectDistributor, cfg.Rebalance.ObjectsPerSecond)
 	storageLocator := util.NewMinioStorageLocator(
 		func(ctx context.Context) ([]util.Container, error) {
-			return dockerClient.SearchContainers(ctx, minioDockerStorageName)
+			cfg, _ := live.get()
+			return dockerClient.SearchContainers(ctx, containerFilter(cfg))
 		},
-		func(storageID string, storage *minioStorage.ObjectStorage) {
+		util.NewEnvCredentialResolver(dockerClient.ReadFile, util.DefaultCredentialEnvs...),
+		func(node util.StorageNode, storage *minioStorage.ObjectStorage) {
 			logrus.WithFields(logrus.Fields{
-				"storageID": storageID,
+				"storageID": node.ID,
+				"address":   node.Address,
+				"bucket":    node.Bucket,
+				"weight":    node.Weight,
+				"zone":      node.Zone,
 			}).Info("adding storage")
-			objectDistributor.AddStorage(storageID, storage)
+			objectDistributor.AddZonedStorage(node.ID, storage, node.Weight, node.Zone)
 		},
 		func(storageID string) {
 			logrus.WithFields(logrus.Fields{
@@ -47,13 +91,57 @@ func run() error {
 			}).Info("removing storage")
 			objectDistributor.RemoveStorage(storageID)
 		},
+		util.WithNodeDefaults(util.NodeDefaults{
+			Port:   cfg.Storage.MinioPort,
+			Bucket: cfg.Storage.Bucket,
+		}),
+		util.WithHealthCheckInterval(cfg.Storage.HealthCheckInterval),
 	)
 
+	router := http.NewServeMux()
+	tusRouter := metrics.InstrumentHandler("tus", tus.Router(objectDistributor, cfg.Tus.BasePath))
+	router.Handle(cfg.Tus.BasePath, tusRouter)
+	router.Handle(cfg.Tus.BasePath+"/", tusRouter)
+	router.Handle(metricsPath, metrics.Handler())
+	adminRouter := admin.Router(objectDistributor, storageLocator.Nodes, rebalancer.Progress, cfg.Readiness.MinReadyNodes)
+	router.Handle("/healthz", adminRouter)
+	router.Handle("/readyz", adminRouter)
+	router.Handle("/admin/", adminRouter)
+	router.Handle("/", metrics.InstrumentHandler("object", handler.Router(objectDistributor)))
+
 	httpServer := &http.Server{
-		Addr: fmt.Sprintf(":3000"),
+		Addr: cfg.HTTP.Address,
 		Handler: gorillaHandlers.RecoveryHandler(
 			gorillaHandlers.RecoveryLogger(logrus.StandardLogger()),
-		)(handler.Router(objectDistributor)),
+		)(router),
+	}
+
+	var s3Server *http.Server
+	if cfg.S3Enabled() {
+		s3Server = &http.Server{
+			Addr: cfg.S3.Address,
+			Handler: gorillaHandlers.RecoveryHandler(
+				gorillaHandlers.RecoveryLogger(logrus.StandardLogger()),
+			)(metrics.InstrumentHandler("s3", s3.Router(objectDistributor, s3.Config{
+				Bucket:      cfg.S3.Bucket,
+				Region:      cfg.S3.Region,
+				Credentials: map[string]string{cfg.S3.AccessKey: cfg.S3.SecretKey},
+			}))),
+		}
+	} else {
+		logrus.Info("S3 credentials are not set, S3 API is disabled")
+	}
+
+	var grpcServer *grpc.Server
+	var grpcListener net.Listener
+	if cfg.GRPC.Address != "" {
+		grpcListener, err = net.Listen("tcp", cfg.GRPC.Address)
+		if err != nil {
+			return err
+		}
+		grpcServer = rpc.NewServer(objectDistributor)
+	} else {
+		logrus.Info("gRPC address is not set, gRPC API is disabled")
 	}
 
 	serverCtx, cancel := context.WithCancel(context.Background())
@@ -64,21 +152,84 @@ func run() error {
 	go func() {
 		defer wg.Done()
 
-		t := time.NewTicker(time.Second)
+		// Events add and remove nodes right away, the event stream is resubscribed whenever it breaks
+		// and right away when a reload changes the container filter. Resubscribing replays events
+		// since the last one received, so none are missed in between.
+		since := time.Now()
+		for serverCtx.Err() == nil {
+			cfg, reloaded := live.get()
+			watchCtx, cancelWatch := context.WithCancel(serverCtx)
+			go func() {
+				for {
+					select {
+					case <-watchCtx.Done():
+						return
+					case <-reloaded:
+						var next config.Config
+						next, reloaded = live.get()
+						if !sameContainerFilter(cfg, next) {
+							cancelWatch()
+							return
+						}
+					}
+				}
+			}()
+
+			var err error
+			since, err = dockerClient.WatchContainers(watchCtx, containerFilter(cfg), since, func(event util.ContainerEvent) {
+				if err := storageLocator.HandleContainerEvent(serverCtx, event); err != nil {
+					logrus.WithError(err).Error("handling container event")
+				}
+			})
+			// Either the gateway stops or the filter changed, neither is an error.
+			stopped := watchCtx.Err() != nil
+			cancelWatch()
+			if stopped {
+				continue
+			}
+			if err != nil {
+				logrus.WithError(err).Error("watching containers")
+			}
+
+			select {
+			case <-serverCtx.Done():
+			case <-time.After(cfg.Discovery.ResubscribeDelay):
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		// The periodic resync is a safety net for missed events and nodes going unhealthy.
+		cfg, reloaded := live.get()
+		t := time.NewTicker(cfg.Discovery.ResyncInterval)
 		defer t.Stop()
 
 		for serverCtx.Err() == nil {
+			if err := storageLocator.Tick(serverCtx); err != nil {
+				logrus.WithError(err).Error("ticking server locator")
+			}
+
 			select {
 			case <-serverCtx.Done():
 				return
 			case <-t.C:
-				if err := storageLocator.Tick(serverCtx); err != nil {
-					logrus.WithError(err).Error("ticking server locator")
-				}
+			case <-reloaded:
+				// Resyncs right away, so containers are matched against a changed filter.
+				cfg, reloaded = live.get()
+				t.Reset(cfg.Discovery.ResyncInterval)
 			}
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rebalancer.Run(serverCtx)
+	}()
+
 	go func() {
 		if err := httpServer.ListenAndServe(); err != nil {
 			logrus.WithError(err).Error("http server")
@@ -86,6 +237,52 @@ func run() error {
 		cancel()
 	}()
 
+	if s3Server != nil {
+		go func() {
+			if err := s3Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logrus.WithError(err).Error("s3 server")
+				cancel()
+			}
+		}()
+	}
+
+	if grpcServer != nil {
+		go func() {
+			if err := grpcServer.Serve(grpcListener); err != nil {
+				logrus.WithError(err).Error("grpc server")
+				cancel()
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		// Reloading instead of restarting keeps in-flight uploads running.
+		r := reloader{
+			live:           live,
+			loadFn:         loadFn,
+			selector:       storageSelector,
+			distributor:    objectDistributor,
+			rebalancer:     rebalancer,
+			storageLocator: storageLocator,
+		}
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		defer signal.Stop(hupChan)
+
+		for {
+			select {
+			case <-serverCtx.Done():
+				return
+			case <-hupChan:
+				logrus.Info("received SIGHUP, reloading config")
+				r.reload()
+			}
+		}
+	}()
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
@@ -96,14 +293,56 @@ func run() error {
 	if err := httpServer.Shutdown(context.Background()); err != nil {
 		logrus.WithError(err).Error("shutting down http server")
 	}
+	if s3Server != nil {
+		if err := s3Server.Shutdown(context.Background()); err != nil {
+			logrus.WithError(err).Error("shutting down s3 server")
+		}
+	}
+	if grpcServer != nil {
+		// Waits for running streams to finish, same as Shutdown does for HTTP requests.
+		grpcServer.GracefulStop()
+	}
 	<-serverCtx.Done()
 
 	wg.Wait()
+	// Repairs started by the last requests are finished rather than cut off.
+	objectDistributor.Close()
 	return nil
 }
 
+func newStorageSelector(cfg config.RingConfig) distributor.StorageSelector {
+	if cfg.Selector == config.SelectorRendezvous {
+		return util.NewRendezvousStorageSelector()
+	}
+	return util.NewConsistentHashStorageSelector(
+		util.WithRingParameters(cfg.PartitionCount, cfg.ReplicationFactor, cfg.Load),
+	)
+}
+
 func main() {
-	if err := run(); err != nil {
+	opts, err := config.Load(os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Fatal("loading config")
+	}
+
+	if opts.PrintConfig {
+		if err := config.Print(os.Stdout, opts.Config); err != nil {
+			logrus.WithError(err).Fatal("printing config")
+		}
+		return
+	}
+
+	if opts.File != "" {
+		logrus.WithField("file", opts.File).Info("loaded config")
+	}
+	loadFn := func() (config.Config, error) {
+		opts, err := config.Load(os.Args[1:], os.LookupEnv, io.Discard)
+		return opts.Config, err
+	}
+	if err := run(opts.Config, loadFn); err != nil {
 		logrus.WithError(err).Error("running application")
 	}
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spacelift-io/homework-object-storage/internal/client/docker"
+	"github.com/spacelift-io/homework-object-storage/internal/config"
+	"github.com/spacelift-io/homework-object-storage/internal/core/distributor"
+	"github.com/spacelift-io/homework-object-storage/internal/util"
+)
+
+// liveConfig holds the config of the running gateway, which changes when it's reloaded.
+type liveConfig struct {
+	l   sync.Mutex
+	cfg config.Config
+	// reloaded is closed and replaced on every reload.
+	reloaded chan struct{}
+}
+
+func newLiveConfig(cfg config.Config) *liveConfig {
+	return &liveConfig{
+		cfg:      cfg,
+		reloaded: make(chan struct{}),
+	}
+}
+
+// get returns the current config and a channel closed once it's reloaded.
+func (c *liveConfig) get() (config.Config, <-chan struct{}) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	return c.cfg, c.reloaded
+}
+
+func (c *liveConfig) set(cfg config.Config) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.cfg = cfg
+	close(c.reloaded)
+	c.reloaded = make(chan struct{})
+}
+
+func containerFilter(cfg config.Config) docker.ContainerFilter {
+	return docker.ContainerFilter{
+		NameContains: cfg.Discovery.ContainerNameFilter,
+		Labels:       cfg.Discovery.ContainerLabels,
+	}
+}
+
+func sameContainerFilter(a, b config.Config) bool {
+	return reflect.DeepEqual(containerFilter(a), containerFilter(b))
+}
+
+// reloader applies changes of live settings to running components. Discovery settings are
+// picked up by the loops watching and resyncing containers once the live config is set.
+type reloader struct {
+	live           *liveConfig
+	loadFn         func() (config.Config, error)
+	selector       distributor.StorageSelector
+	distributor    *distributor.ObjectDistributor
+	rebalancer     *distributor.Rebalancer
+	storageLocator *util.MinioStorageLocator
+}
+
+func (r reloader) reload() {
+	next, err := r.loadFn()
+	if err != nil {
+		logrus.WithError(err).Error("reloading config, keeping the current one")
+		return
+	}
+
+	current, _ := r.live.get()
+	next, rejected := current.Reload(next)
+	for _, name := range rejected {
+		logrus.WithField("setting", name).Warn("setting can't change while running, keeping its current value until restart")
+	}
+
+	changes := current.Changes(next)
+	if len(changes) == 0 {
+		logrus.Info("reloaded config, nothing changed")
+		return
+	}
+
+	// The level was validated when loading the config.
+	level, _ := logrus.ParseLevel(next.LogLevel)
+	logrus.SetLevel(level)
+	r.rebalancer.SetObjectsPerSecond(next.Rebalance.ObjectsPerSecond)
+	r.storageLocator.SetHealthCheckInterval(next.Storage.HealthCheckInterval)
+	// Only the consistent hash ring has parameters, switching selectors isn't a live setting.
+	if ring, ok := r.selector.(*util.ConsistentHashStorageSelector); ok && next.Ring != current.Ring {
+		logrus.WithFields(logrus.Fields{
+			"partitionCount":    next.Ring.PartitionCount,
+			"replicationFactor": next.Ring.ReplicationFactor,
+			"load":              next.Ring.Load,
+		}).Info("changing ring parameters, objects will be rebalanced")
+		r.distributor.ReconfigureSelector(func() {
+			ring.Reconfigure(util.WithRingParameters(next.Ring.PartitionCount, next.Ring.ReplicationFactor, next.Ring.Load))
+		})
+	}
+	r.live.set(next)
+
+	logrus.WithField("settings", changes).Info("reloaded config")
+}